@@ -9,6 +9,42 @@ import (
 
 type Error = apierror.Error
 
+// The error reported in the body of an [Error].
+//
+// This is an alias to an internal type.
+type ErrorErrors = apierror.ErrorErrors
+
+// IsNotFound reports whether err is, or wraps, an [*Error] with status 404 Not
+// Found.
+func IsNotFound(err error) bool {
+	return apierror.IsNotFound(err)
+}
+
+// IsValidationError reports whether err is, or wraps, an [*Error] with status 400
+// Bad Request or 422 Unprocessable Entity, which the API returns for invalid
+// parameters.
+func IsValidationError(err error) bool {
+	return apierror.IsValidationError(err)
+}
+
+// IsConflict reports whether err is, or wraps, an [*Error] with status 409
+// Conflict.
+func IsConflict(err error) bool {
+	return apierror.IsConflict(err)
+}
+
+// IsRateLimited reports whether err is, or wraps, an [*Error] with status 429 Too
+// Many Requests.
+func IsRateLimited(err error) bool {
+	return apierror.IsRateLimited(err)
+}
+
+// IsAuth reports whether err is, or wraps, an [*Error] with status 401
+// Unauthorized or 403 Forbidden.
+func IsAuth(err error) bool {
+	return apierror.IsAuth(err)
+}
+
 // This is an alias to an internal type.
 type AccountsType = shared.AccountsType
 
@@ -19,3 +55,19 @@ type AsyncResponse = shared.AsyncResponse
 //
 // This is an alias to an internal type.
 type Currency = shared.Currency
+
+// RetryExhaustedError is returned when a request was retried and its final
+// attempt still failed with a retryable error.
+//
+// This is an alias to an internal type.
+type RetryExhaustedError = apierror.RetryExhaustedError
+
+// CircuitOpenError is returned for a request which was rejected by an open
+// circuit breaker without being sent.
+//
+// This is an alias to an internal type.
+type CircuitOpenError = apierror.CircuitOpenError
+
+// ErrCircuitOpen is matched by errors.Is for requests which were rejected by a
+// circuit breaker without being sent.
+var ErrCircuitOpen = apierror.ErrCircuitOpen
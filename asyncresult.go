@@ -0,0 +1,84 @@
+package moderntreasury
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+)
+
+// AsyncOptions configures how an [AsyncResult] waits for its object.
+type AsyncOptions struct {
+	// The delay before the second poll, which doubles after each poll. Defaults to
+	// 500 milliseconds.
+	Interval time.Duration
+	// The longest delay between polls. Defaults to 10 seconds.
+	MaxInterval time.Duration
+	// The longest time to wait for the object to be created. Defaults to 1 minute.
+	Timeout time.Duration
+}
+
+// AsyncResult is a handle on an object which is being created asynchronously, as
+// returned by the NewAsync methods.
+type AsyncResult[T any] struct {
+	// The response of the request which started the creation.
+	Response *AsyncResponse
+
+	opts AsyncOptions
+	get  func(ctx context.Context, id string, opts ...option.RequestOption) (*T, error)
+}
+
+func newAsyncResult[T any](res *AsyncResponse, opts AsyncOptions, get func(ctx context.Context, id string, opts ...option.RequestOption) (*T, error)) *AsyncResult[T] {
+	if opts.Interval <= 0 {
+		opts.Interval = 500 * time.Millisecond
+	}
+	if opts.MaxInterval <= 0 {
+		opts.MaxInterval = 10 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Minute
+	}
+	return &AsyncResult[T]{Response: res, opts: opts, get: get}
+}
+
+// Wait polls for the object with an exponential backoff until it is created, and
+// returns it. Until then, the object is not found, which is not an error. If it
+// isn't created within the timeout, Wait returns an error which wraps
+// [context.DeadlineExceeded].
+func (r *AsyncResult[T]) Wait(ctx context.Context, opts ...option.RequestOption) (*T, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.opts.Timeout)
+	defer cancel()
+
+	interval := r.opts.Interval
+	for {
+		res, err := r.get(ctx, r.Response.ID, opts...)
+		if err == nil {
+			return res, nil
+		}
+		if ctx.Err() == nil && !IsNotFound(err) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%s %s was not created within %s: %w", r.Response.Object, r.Response.ID, r.opts.Timeout, ctx.Err())
+		case <-time.After(interval):
+		}
+		interval *= 2
+		if interval > r.opts.MaxInterval {
+			interval = r.opts.MaxInterval
+		}
+	}
+}
+
+// ResolveAsync returns a handle on the payment order which is created by a
+// request to [PaymentOrderService.NewAsync].
+func (r *PaymentOrderService) ResolveAsync(res *AsyncResponse, opts AsyncOptions) *AsyncResult[PaymentOrder] {
+	return newAsyncResult(res, opts, r.Get)
+}
+
+// ResolveAsync returns a handle on the incoming payment detail which is created
+// by a request to [IncomingPaymentDetailService.NewAsync].
+func (r *IncomingPaymentDetailService) ResolveAsync(res *AsyncResponse, opts AsyncOptions) *AsyncResult[IncomingPaymentDetail] {
+	return newAsyncResult(res, opts, r.Get)
+}
@@ -0,0 +1,66 @@
+package moderntreasury_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+	"github.com/Modern-Treasury/modern-treasury-go/testutil/mtfake"
+)
+
+// notFoundFor returns a middleware which responds to the first n requests to get
+// an object with 404 Not Found, as if the object wasn't created yet.
+func notFoundFor(n int) option.Middleware {
+	return func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		if req.Method != http.MethodGet || n <= 0 {
+			return next(req)
+		}
+		n--
+		return &http.Response{
+			StatusCode: http.StatusNotFound,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(`{"errors":{"code":"resource_not_found","message":"Not found"}}`)),
+			Request:    req,
+		}, nil
+	}
+}
+
+func TestAsyncResult(t *testing.T) {
+	srv := mtfake.NewServer()
+	defer srv.Close()
+	client := moderntreasury.NewClient(append(srv.Options(), option.WithMiddleware(notFoundFor(2)))...)
+	ctx := context.Background()
+
+	res, err := client.PaymentOrders.NewAsync(ctx, moderntreasury.PaymentOrderNewAsyncParams{
+		Amount:               moderntreasury.F(int64(1000)),
+		Direction:            moderntreasury.F(moderntreasury.PaymentOrderNewAsyncParamsDirectionCredit),
+		OriginatingAccountID: moderntreasury.F("0f8e3719-3dfd-4613-9bbf-c0333781b59f"),
+		Type:                 moderntreasury.F(moderntreasury.PaymentOrderTypeACH),
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	paymentOrder, err := client.PaymentOrders.ResolveAsync(res, moderntreasury.AsyncOptions{Interval: time.Millisecond}).Wait(ctx)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if paymentOrder.ID != res.ID || paymentOrder.Amount != 1000 {
+		t.Fatalf("unexpected payment order: %+v", paymentOrder)
+	}
+
+	// Objects which are never created time out.
+	missing := &moderntreasury.AsyncResponse{ID: "0f8e3719-3dfd-4613-9bbf-c0333781b59f", Object: "incoming_payment_detail"}
+	_, err = client.IncomingPaymentDetails.ResolveAsync(missing, moderntreasury.AsyncOptions{
+		Interval: time.Millisecond,
+		Timeout:  50 * time.Millisecond,
+	}).Wait(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline exceeded error, got %v", err)
+	}
+}
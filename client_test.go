@@ -4,8 +4,12 @@ package moderntreasury_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -95,3 +99,414 @@ func TestContextDeadline(t *testing.T) {
 		}
 	}
 }
+
+// statusTransport responds to every request with the given status code and
+// counts the attempts it receives.
+type statusTransport struct {
+	statusCode int
+	attempts   int
+}
+
+func (t *statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.attempts += 1
+	return &http.Response{
+		StatusCode: t.statusCode,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestRetryPolicy(t *testing.T) {
+	transport := &statusTransport{statusCode: http.StatusConflict}
+	client := moderntreasury.NewClient(
+		option.WithBaseURL("http://127.0.0.1:4010"),
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithHTTPClient(&http.Client{Transport: transport}),
+		option.WithMaxRetries(3),
+		option.WithRetryPolicy(option.FullJitterRetryPolicy(time.Millisecond, 2*time.Millisecond)),
+	)
+	_, err := client.LedgerTransactions.Update(context.Background(), "id", moderntreasury.LedgerTransactionUpdateParams{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if transport.attempts != 4 {
+		t.Fatalf("expected 4 attempts, got %d", transport.attempts)
+	}
+
+	transport.attempts = 0
+	_, err = client.PaymentOrders.New(context.Background(), moderntreasury.PaymentOrderNewParams{},
+		option.WithRetryPolicy(option.SkipStatusRetryPolicy(nil, http.StatusConflict)),
+	)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if transport.attempts != 1 {
+		t.Fatalf("expected 1 attempt, got %d", transport.attempts)
+	}
+}
+
+func TestIdempotentRetryPolicy(t *testing.T) {
+	transport := &statusTransport{statusCode: http.StatusInternalServerError}
+	client := moderntreasury.NewClient(
+		option.WithBaseURL("http://127.0.0.1:4010"),
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithHTTPClient(&http.Client{Transport: transport}),
+		option.WithRetryPolicy(option.IdempotentRetryPolicy(
+			option.DecorrelatedJitterRetryPolicy(time.Millisecond, 2*time.Millisecond),
+		)),
+	)
+	_, err := client.Counterparties.New(context.Background(), moderntreasury.CounterpartyNewParams{})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if transport.attempts != 1 {
+		t.Fatalf("expected 1 attempt, got %d", transport.attempts)
+	}
+
+	transport.attempts = 0
+	_, err = client.Counterparties.New(context.Background(), moderntreasury.CounterpartyNewParams{},
+		option.WithIdempotencyKey("counterparty-42"),
+	)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if transport.attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", transport.attempts)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	transport := &statusTransport{statusCode: http.StatusServiceUnavailable}
+	client := moderntreasury.NewClient(
+		option.WithBaseURL("http://127.0.0.1:4010"),
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithHTTPClient(&http.Client{Transport: transport}),
+		option.WithRetryPolicy(option.FullJitterRetryPolicy(time.Millisecond, 2*time.Millisecond)),
+	)
+	_, err := client.PaymentOrders.Get(context.Background(), "id")
+	var exhausted *moderntreasury.RetryExhaustedError
+	if !errors.As(err, &exhausted) {
+		t.Fatalf("expected a RetryExhaustedError, got %v", err)
+	}
+	if len(exhausted.Attempts) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(exhausted.Attempts))
+	}
+	var apierr *moderntreasury.Error
+	if !errors.As(err, &apierr) || apierr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected the final attempt's error to be wrapped, got %v", err)
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	transport := &statusTransport{statusCode: http.StatusServiceUnavailable}
+	client := moderntreasury.NewClient(
+		option.WithBaseURL("http://127.0.0.1:4010"),
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithHTTPClient(&http.Client{Transport: transport}),
+		option.WithMaxRetries(10),
+		option.WithRetryBudget(time.Second),
+	)
+	start := time.Now()
+	_, err := client.PaymentOrders.Get(context.Background(), "id")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the retry budget to bound the request, took %s", elapsed)
+	}
+	var exhausted *moderntreasury.RetryExhaustedError
+	if !errors.As(err, &exhausted) {
+		t.Fatalf("expected a RetryExhaustedError, got %v", err)
+	}
+	if transport.attempts >= 10 {
+		t.Fatalf("expected fewer than 10 attempts, got %d", transport.attempts)
+	}
+}
+
+func TestRetryBudgetInFlight(t *testing.T) {
+	client := moderntreasury.NewClient(
+		option.WithBaseURL("http://127.0.0.1:4010"),
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithHTTPClient(&http.Client{Transport: &neverTransport{}}),
+		option.WithRetryBudget(50*time.Millisecond),
+	)
+	start := time.Now()
+	_, err := client.PaymentOrders.Get(context.Background(), "id")
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the retry budget to cancel the attempt, took %s", elapsed)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestContextCancelDuringRetryDelay(t *testing.T) {
+	client := moderntreasury.NewClient(
+		option.WithBaseURL("http://127.0.0.1:4010"),
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithHTTPClient(&http.Client{Transport: &statusTransport{statusCode: http.StatusServiceUnavailable}}),
+		option.WithRetryPolicy(option.RetryPolicyFunc(func(option.RetryAttempt) (time.Duration, bool) {
+			return time.Minute, true
+		})),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.PaymentOrders.Get(ctx, "id")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the retry delay to be interrupted, took %s", elapsed)
+	}
+}
+
+// rateLimitTransport responds to every request with a 200 that reports that the
+// rate limit is exhausted until the next second.
+type rateLimitTransport struct{}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Type":          []string{"application/json"},
+			"X-Ratelimit-Remaining": []string{"0"},
+			"X-Ratelimit-Reset":     []string{"1"},
+		},
+		Body:    io.NopCloser(strings.NewReader(`{}`)),
+		Request: req,
+	}, nil
+}
+
+func TestRateLimiter(t *testing.T) {
+	client := moderntreasury.NewClient(
+		option.WithBaseURL("http://127.0.0.1:4010"),
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithHTTPClient(&http.Client{Transport: &rateLimitTransport{}}),
+		option.WithRateLimiter(option.NewRateLimiter(100, 10)),
+	)
+	start := time.Now()
+	if _, err := client.PaymentOrders.Get(context.Background(), "id"); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if _, err := client.Counterparties.Get(context.Background(), "id"); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Fatalf("expected the second request to wait for the rate limit to reset, took %s", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.PaymentOrders.Get(ctx, "id"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error while waiting for the rate limiter, got %v", err)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	transport := &statusTransport{statusCode: http.StatusServiceUnavailable}
+	var transitions []string
+	breaker := option.NewCircuitBreaker(option.CircuitBreakerConfig{
+		FailureThreshold: 2,
+		OpenTimeout:      50 * time.Millisecond,
+		PerEndpoint:      true,
+		OnStateChange: func(key string, from, to option.CircuitState) {
+			transitions = append(transitions, key+": "+from.String()+" -> "+to.String())
+		},
+	})
+	client := moderntreasury.NewClient(
+		option.WithBaseURL("http://127.0.0.1:4010"),
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithHTTPClient(&http.Client{Transport: transport}),
+		option.WithMaxRetries(5),
+		option.WithRetryPolicy(option.FullJitterRetryPolicy(time.Millisecond, 2*time.Millisecond)),
+		option.WithCircuitBreaker(breaker),
+	)
+	_, err := client.PaymentOrders.List(context.Background(), moderntreasury.PaymentOrderListParams{})
+	if !errors.Is(err, moderntreasury.ErrCircuitOpen) {
+		t.Fatalf("expected the circuit to open, got %v", err)
+	}
+	if transport.attempts != 2 {
+		t.Fatalf("expected 2 attempts before the circuit opened, got %d", transport.attempts)
+	}
+	if state := breaker.State("127.0.0.1:4010/api/payment_orders"); state != option.CircuitOpen {
+		t.Fatalf("expected the circuit to be open, got %s", state)
+	}
+
+	// Other endpoints have their own circuit.
+	transport.statusCode = http.StatusOK
+	if _, err := client.Counterparties.Get(context.Background(), "id"); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if _, err := client.PaymentOrders.List(context.Background(), moderntreasury.PaymentOrderListParams{}); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	expected := []string{
+		"127.0.0.1:4010/api/payment_orders: closed -> open",
+		"127.0.0.1:4010/api/payment_orders: open -> half-open",
+		"127.0.0.1:4010/api/payment_orders: half-open -> closed",
+	}
+	if strings.Join(transitions, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected transitions:\n%s", strings.Join(transitions, "\n"))
+	}
+}
+
+func TestCircuitBreakerCallerCancel(t *testing.T) {
+	breaker := option.NewCircuitBreaker(option.CircuitBreakerConfig{FailureThreshold: 2})
+	client := moderntreasury.NewClient(
+		option.WithBaseURL("http://127.0.0.1:4010"),
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithHTTPClient(&http.Client{Transport: &neverTransport{}}),
+		option.WithCircuitBreaker(breaker),
+	)
+
+	// Requests whose caller gave up don't count against the host.
+	for i := 0; i < 3; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err := client.PaymentOrders.Get(ctx, "id")
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+		}
+	}
+	if state := breaker.State("127.0.0.1:4010"); state != option.CircuitClosed {
+		t.Fatalf("expected the circuit to stay closed, got %s", state)
+	}
+
+	// Attempts which time out on their own do.
+	for i := 0; i < 2; i++ {
+		client.PaymentOrders.Get(context.Background(), "id", option.WithRequestTimeout(10*time.Millisecond), option.WithMaxRetries(0))
+	}
+	if state := breaker.State("127.0.0.1:4010"); state != option.CircuitOpen {
+		t.Fatalf("expected the circuit to open, got %s", state)
+	}
+}
+
+// headerTransport records the headers of every request it receives.
+type headerTransport struct {
+	headers []http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.headers = append(t.headers, req.Header.Clone())
+	return &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestIdempotencyKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	newClient := func(transport http.RoundTripper) (*moderntreasury.Client, option.IdempotencyKeyStore) {
+		store, err := option.NewFileIdempotencyKeyStore(path, "payouts")
+		if err != nil {
+			t.Fatalf("err should be nil: %s", err.Error())
+		}
+		return moderntreasury.NewClient(
+			option.WithBaseURL("http://127.0.0.1:4010"),
+			option.WithAPIKey("APIKey"),
+			option.WithOrganizationID("my-organization-ID"),
+			option.WithHTTPClient(&http.Client{Transport: transport}),
+			option.WithRetryPolicy(option.FullJitterRetryPolicy(time.Millisecond, 2*time.Millisecond)),
+		), store
+	}
+
+	transport := &headerTransport{}
+	client, store := newClient(transport)
+	client.PaymentOrders.New(context.Background(), moderntreasury.PaymentOrderNewParams{},
+		option.WithIdempotencyKeyFrom(store, "invoice-123"),
+	)
+	// A restarted worker re-runs the same request with a fresh client and store.
+	client, store = newClient(transport)
+	client.PaymentOrders.New(context.Background(), moderntreasury.PaymentOrderNewParams{},
+		option.WithIdempotencyKeyFrom(store, "invoice-123"),
+	)
+	if len(transport.headers) != 6 {
+		t.Fatalf("expected 6 attempts, got %d", len(transport.headers))
+	}
+	key := transport.headers[0].Get("Idempotency-Key")
+	for _, header := range transport.headers {
+		if header.Get("Idempotency-Key") != key {
+			t.Fatalf("expected every attempt to use the key %q, got %q", key, header.Get("Idempotency-Key"))
+		}
+	}
+
+	if err := store.Reset(context.Background(), "invoice-123"); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	_, store = newClient(transport)
+	reset, err := store.IdempotencyKey(context.Background(), "invoice-123")
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if reset == key {
+		t.Fatal("expected a new key after a reset")
+	}
+}
+
+// bodyTransport responds to every request with the given status code and body.
+type bodyTransport struct {
+	statusCode  int
+	contentType string
+	body        string
+}
+
+func (t *bodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: t.statusCode,
+		Header:     http.Header{"Content-Type": []string{t.contentType}},
+		Body:       io.NopCloser(strings.NewReader(t.body)),
+		Request:    req,
+	}, nil
+}
+
+func TestErrorBody(t *testing.T) {
+	transport := &bodyTransport{
+		statusCode:  http.StatusUnprocessableEntity,
+		contentType: "application/json",
+		body:        `{"errors":{"code":"parameter_invalid","message":"Amount must be positive","parameter":"amount"}}`,
+	}
+	client := moderntreasury.NewClient(
+		option.WithBaseURL("http://127.0.0.1:4010"),
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithHTTPClient(&http.Client{Transport: transport}),
+	)
+	_, err := client.PaymentOrders.New(context.Background(), moderntreasury.PaymentOrderNewParams{})
+	var apierr *moderntreasury.Error
+	if !errors.As(err, &apierr) {
+		t.Fatalf("expected a *moderntreasury.Error, got %v", err)
+	}
+	if apierr.Errors.Code != "parameter_invalid" || apierr.Errors.Message != "Amount must be positive" || apierr.Errors.Parameter != "amount" {
+		t.Fatalf("unexpected errors: %+v", apierr.Errors)
+	}
+	if !moderntreasury.IsValidationError(err) || moderntreasury.IsNotFound(err) || moderntreasury.IsConflict(err) ||
+		moderntreasury.IsRateLimited(err) || moderntreasury.IsAuth(err) {
+		t.Fatalf("unexpected classification of %v", err)
+	}
+	if first, second := err.Error(), err.Error(); first != second || !strings.Contains(first, "Amount must be positive") {
+		t.Fatalf("expected a stable error message with the body, got %q and %q", first, second)
+	}
+
+	transport.statusCode = http.StatusNotFound
+	transport.contentType = "text/html"
+	transport.body = "<html>Not Found</html>"
+	_, err = client.PaymentOrders.Get(context.Background(), "id")
+	if !moderntreasury.IsNotFound(err) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "<html>Not Found</html>") {
+		t.Fatalf("expected the error message to contain the body, got %q", err.Error())
+	}
+}
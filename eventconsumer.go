@@ -0,0 +1,391 @@
+package moderntreasury
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+)
+
+// EventDispatcher delivers events to the callbacks registered for their
+// resource. It is implemented by [WebhookHandler], so that events which are
+// polled are handled by the same callbacks as webhooks.
+type EventDispatcher interface {
+	Dispatch(ctx context.Context, event WebhookEvent) error
+}
+
+// EventConsumerCheckpoint is the progress of an [EventConsumer]: the time up to
+// which all events were delivered, the events which were delivered around and
+// after it, which are skipped when they are listed again, and the number of
+// times each event which failed was attempted.
+type EventConsumerCheckpoint struct {
+	EventTime time.Time            `json:"event_time"`
+	Delivered map[string]time.Time `json:"delivered"`
+	Attempts  map[string]int       `json:"attempts,omitempty"`
+}
+
+// EventCheckpointStore persists the checkpoint of an [EventConsumer], so that it
+// resumes where it left off when it is restarted.
+type EventCheckpointStore interface {
+	// Load returns the saved checkpoint, or nil if none was saved.
+	Load(ctx context.Context) (*EventConsumerCheckpoint, error)
+	Save(ctx context.Context, checkpoint EventConsumerCheckpoint) error
+}
+
+// NewMemoryEventCheckpointStore returns an [EventCheckpointStore] which keeps the
+// checkpoint in memory.
+func NewMemoryEventCheckpointStore() EventCheckpointStore {
+	return &memoryEventCheckpointStore{}
+}
+
+type memoryEventCheckpointStore struct {
+	mu         sync.Mutex
+	checkpoint *EventConsumerCheckpoint
+}
+
+func (s *memoryEventCheckpointStore) Load(ctx context.Context) (*EventConsumerCheckpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.checkpoint == nil {
+		return nil, nil
+	}
+	checkpoint := EventConsumerCheckpoint{EventTime: s.checkpoint.EventTime, Delivered: map[string]time.Time{}, Attempts: map[string]int{}}
+	for id, eventTime := range s.checkpoint.Delivered {
+		checkpoint.Delivered[id] = eventTime
+	}
+	for id, attempts := range s.checkpoint.Attempts {
+		checkpoint.Attempts[id] = attempts
+	}
+	return &checkpoint, nil
+}
+
+func (s *memoryEventCheckpointStore) Save(ctx context.Context, checkpoint EventConsumerCheckpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checkpoint = &checkpoint
+	return nil
+}
+
+// EventConsumerOptions configures an [EventConsumer].
+type EventConsumerOptions struct {
+	// Filters of the events to consume, such as Resource or EventName. The event
+	// time bounds are set by the consumer.
+	Params EventListParams
+	// Persists the progress of the consumer. Defaults to a store in memory.
+	Store EventCheckpointStore
+	// The time from which events are consumed when the store has no checkpoint.
+	// Defaults to the time the consumer is created.
+	Start time.Time
+	// The number of events which are delivered at the same time. Events of the
+	// same entity are always delivered one at a time, in order. Defaults to 1.
+	Concurrency int
+	// The time between polls of [EventConsumer.Run]. Defaults to 10 seconds.
+	PollInterval time.Duration
+	// How far before the checkpoint events are listed again, so that events which
+	// are listed late, or whose time is truncated in the list's query, are not
+	// missed. Defaults to 1 minute.
+	Overlap time.Duration
+	// The length of the windows of time which events are listed in. Each window
+	// is delivered and checkpointed before the next one is listed, and windows
+	// are narrowed, down to a second, until they hold at most BatchSize events.
+	// Defaults to 1 hour.
+	Window time.Duration
+	// The number of events which are held at once. Defaults to 1000.
+	BatchSize int
+	// The number of times an event is attempted before it is given up on, so that
+	// an event which keeps failing doesn't hold back the consumer. Defaults to 10.
+	MaxAttempts int
+	// Called with each event which is given up on, and the error of its last
+	// attempt, e.g. to store it for inspection. If it returns an error, the event
+	// is attempted again. If DeadLetter is nil, the event's error is returned by
+	// the poll which gives up on it.
+	DeadLetter func(ctx context.Context, event Event, err error) error
+}
+
+// EventConsumer polls the Events API and delivers the events to an
+// [EventDispatcher], as a fallback for webhooks which were not received. Events
+// are delivered at least once: the checkpoint only advances past events which
+// were delivered or given up on, and events are de-duplicated by their ID. When
+// an event of an entity fails, the later events of the entity are held back
+// until it is delivered or given up on.
+type EventConsumer struct {
+	service    *EventService
+	dispatcher EventDispatcher
+	opts       EventConsumerOptions
+	reqOpts    []option.RequestOption
+}
+
+// NewConsumer returns an [EventConsumer] which delivers the events of the
+// service to the dispatcher, such as a [WebhookHandler].
+func (r *EventService) NewConsumer(dispatcher EventDispatcher, opts EventConsumerOptions, reqOpts ...option.RequestOption) *EventConsumer {
+	if opts.Store == nil {
+		opts.Store = NewMemoryEventCheckpointStore()
+	}
+	if opts.Start.IsZero() {
+		opts.Start = time.Now()
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 10 * time.Second
+	}
+	if opts.Overlap <= 0 {
+		opts.Overlap = time.Minute
+	}
+	if opts.Window <= 0 {
+		opts.Window = time.Hour
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 10
+	}
+	return &EventConsumer{service: r, dispatcher: dispatcher, opts: opts, reqOpts: reqOpts}
+}
+
+// Run polls for events until the context is done, and returns its error. Errors
+// of polls are passed to onError, if it is not nil, and the consumer polls again
+// after the poll interval.
+func (c *EventConsumer) Run(ctx context.Context, onError func(error)) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+		if _, err := c.Poll(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+		timer.Reset(c.opts.PollInterval)
+	}
+}
+
+// Poll lists the events since the checkpoint, window by window, delivers those
+// which were not delivered yet and saves the checkpoint after each window. It
+// stops at the first window with an event which failed, and returns the number
+// of events which were delivered. It must not be called concurrently.
+func (c *EventConsumer) Poll(ctx context.Context) (int, error) {
+	checkpoint, err := c.opts.Store.Load(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if checkpoint == nil {
+		checkpoint = &EventConsumerCheckpoint{EventTime: c.opts.Start}
+	}
+	if checkpoint.Delivered == nil {
+		checkpoint.Delivered = map[string]time.Time{}
+	}
+	if checkpoint.Attempts == nil {
+		checkpoint.Attempts = map[string]int{}
+	}
+
+	delivered := 0
+	from := checkpoint.EventTime.Add(-c.opts.Overlap)
+	window := c.opts.Window
+	for {
+		to := from.Add(window)
+		last := !to.Before(time.Now())
+		// Windows of a second can't be narrowed, as the query's times are in
+		// seconds, so all of their events are listed.
+		limit := c.opts.BatchSize
+		if window <= time.Second {
+			limit = 0
+		}
+		events, full, err := c.list(ctx, checkpoint, from, to, last, limit)
+		if err != nil {
+			return delivered, err
+		}
+		if full {
+			window /= 2
+			continue
+		}
+
+		n, err := c.deliverWindow(ctx, checkpoint, events, to, last)
+		delivered += n
+		if err != nil || last {
+			return delivered, err
+		}
+		from = to
+		if window < c.opts.Window {
+			window *= 2
+		}
+	}
+}
+
+// list returns the events of the window which were not delivered yet, sorted by
+// time. The last window has no end. If the window has more than limit events, it
+// stops listing and reports that the window is full.
+func (c *EventConsumer) list(ctx context.Context, checkpoint *EventConsumerCheckpoint, from time.Time, to time.Time, last bool, limit int) (events []Event, full bool, err error) {
+	params := c.opts.Params
+	params.EventTimeStart = F(from)
+	if !last {
+		params.EventTimeEnd = F(to)
+	}
+	iter := c.service.ListAutoPaging(ctx, params, c.reqOpts...)
+	defer iter.Close()
+	for iter.Next() {
+		event := iter.Current()
+		if _, ok := checkpoint.Delivered[event.ID]; ok {
+			continue
+		}
+		if limit > 0 && len(events) == limit {
+			return nil, true, nil
+		}
+		events = append(events, event)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, false, err
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].EventTime.Before(events[j].EventTime)
+	})
+	return events, false, nil
+}
+
+// deliverWindow delivers the events of the window which ends at the given time,
+// and saves the checkpoint.
+func (c *EventConsumer) deliverWindow(ctx context.Context, checkpoint *EventConsumerCheckpoint, events []Event, to time.Time, last bool) (int, error) {
+	results := c.deliver(ctx, checkpoint, events)
+
+	// The checkpoint advances to the first event which wasn't delivered, or past
+	// the window if all of its events were.
+	delivered := 0
+	var failed *Event
+	var firstErr, gaveUpErr error
+	for i := range events {
+		event, result := events[i], results[i]
+		if result.err != nil {
+			if result.attempted && ctx.Err() == nil {
+				checkpoint.Attempts[event.ID]++
+			}
+			if failed == nil {
+				failed = &events[i]
+				firstErr = result.err
+			}
+			continue
+		}
+		delete(checkpoint.Attempts, event.ID)
+		if !result.gaveUp {
+			delivered++
+		} else if gaveUpErr == nil {
+			gaveUpErr = result.gaveUpErr
+		}
+		checkpoint.Delivered[event.ID] = event.EventTime
+		if failed == nil && event.EventTime.After(checkpoint.EventTime) {
+			checkpoint.EventTime = event.EventTime
+		}
+	}
+	switch {
+	case failed != nil && failed.EventTime.Before(checkpoint.EventTime):
+		checkpoint.EventTime = failed.EventTime
+	case failed == nil && !last && to.After(checkpoint.EventTime):
+		checkpoint.EventTime = to
+	}
+	// The IDs are kept for as long as their events may be listed again, which is a
+	// second longer than the overlap as the query's times are in seconds.
+	for id, eventTime := range checkpoint.Delivered {
+		if eventTime.Before(checkpoint.EventTime.Add(-c.opts.Overlap - time.Second)) {
+			delete(checkpoint.Delivered, id)
+		}
+	}
+	if err := c.opts.Store.Save(ctx, *checkpoint); err != nil {
+		return delivered, err
+	}
+	if failed != nil {
+		return delivered, fmt.Errorf("could not deliver event %s of %s %s: %w", failed.ID, failed.Resource, failed.EntityID, firstErr)
+	}
+	return delivered, gaveUpErr
+}
+
+// giveUp passes the event to the DeadLetter of the options. Without one, it
+// returns an error which reports that the event was given up on.
+func (c *EventConsumer) giveUp(ctx context.Context, event Event, err error) error {
+	if c.opts.DeadLetter == nil {
+		return fmt.Errorf("gave up on event %s of %s %s after %d attempts: %w", event.ID, event.Resource, event.EntityID, c.opts.MaxAttempts, err)
+	}
+	if err := c.opts.DeadLetter(ctx, event, err); err != nil {
+		return fmt.Errorf("could not dead-letter event %s of %s %s: %w", event.ID, event.Resource, event.EntityID, err)
+	}
+	return nil
+}
+
+// deliveryResult is the outcome of delivering an event.
+type deliveryResult struct {
+	// The error of the event, unless it was delivered or given up on.
+	err error
+	// Whether the event was dispatched, rather than held back.
+	attempted bool
+	// Whether the event was given up on after MaxAttempts.
+	gaveUp bool
+	// If the event was given up on without a DeadLetter, the error reporting it.
+	gaveUpErr error
+}
+
+// deliver delivers the events, which are sorted by time, and returns the result
+// of each. Events are distributed among the workers by their entity, so that the
+// events of an entity are delivered in order by the same worker. An event which
+// fails holds back the later events of its entity, unless it is given up on.
+func (c *EventConsumer) deliver(ctx context.Context, checkpoint *EventConsumerCheckpoint, events []Event) []deliveryResult {
+	results := make([]deliveryResult, len(events))
+	queues := make([][]int, c.opts.Concurrency)
+	for i, event := range events {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(event.EntityID))
+		worker := int(h.Sum32() % uint32(c.opts.Concurrency))
+		queues[worker] = append(queues[worker], i)
+	}
+
+	var wg sync.WaitGroup
+	for _, queue := range queues {
+		if len(queue) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(queue []int) {
+			defer wg.Done()
+			failed := map[string]error{}
+			for _, i := range queue {
+				event := events[i]
+				if err, ok := failed[event.EntityID]; ok {
+					results[i].err = err
+					continue
+				}
+				if err := ctx.Err(); err != nil {
+					results[i].err = err
+					continue
+				}
+				results[i].attempted = true
+				err := c.dispatcher.Dispatch(ctx, WebhookEvent{
+					ID:       event.ID,
+					Topic:    event.Resource,
+					LiveMode: event.LiveMode,
+					Event:    event.EventName,
+					Data:     event.data(),
+				})
+				// The attempts are only read while the events are delivered.
+				if err != nil && ctx.Err() == nil && checkpoint.Attempts[event.ID]+1 >= c.opts.MaxAttempts {
+					err = c.giveUp(ctx, event, err)
+					if err == nil || c.opts.DeadLetter == nil {
+						results[i].gaveUp = true
+						results[i].gaveUpErr = err
+						err = nil
+					}
+				}
+				if err != nil {
+					results[i].err = err
+					failed[event.EntityID] = err
+				}
+			}
+		}(queue)
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,209 @@
+package moderntreasury_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+	"github.com/Modern-Treasury/modern-treasury-go/testutil/mtfake"
+)
+
+func TestEventConsumer(t *testing.T) {
+	srv := mtfake.NewServer()
+	defer srv.Close()
+	client := moderntreasury.NewClient(srv.Options()...)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var names []string
+	fail := true
+	handler := client.Webhooks.Handler(moderntreasury.WebhookHandlerOptions{Keys: []string{"key"}}).
+		OnCounterparty(func(ctx context.Context, event moderntreasury.WebhookEvent, counterparty *moderntreasury.Counterparty) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if counterparty.Name == "Bob" && fail {
+				fail = false
+				return errors.New("database is down")
+			}
+			names = append(names, event.Event+" "+counterparty.Name)
+			return nil
+		})
+
+	store := moderntreasury.NewMemoryEventCheckpointStore()
+	consumer := client.Events.NewConsumer(handler, moderntreasury.EventConsumerOptions{
+		Store:       store,
+		Start:       time.Now().Add(-time.Minute),
+		Concurrency: 4,
+	})
+
+	alice, err := client.Counterparties.New(ctx, moderntreasury.CounterpartyNewParams{Name: moderntreasury.F("Alice")})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	bob, err := client.Counterparties.New(ctx, moderntreasury.CounterpartyNewParams{Name: moderntreasury.F("Bob")})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if _, err := client.Counterparties.Update(ctx, bob.ID, moderntreasury.CounterpartyUpdateParams{Name: moderntreasury.F("Bobby")}); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+
+	// Bob's creation fails, so his update is held back, but Alice's creation is
+	// delivered.
+	n, err := consumer.Poll(ctx)
+	if err == nil || n != 1 {
+		t.Fatalf("expected 1 delivered event and an error, got %d, %v", n, err)
+	}
+	// The retry delivers Bob's events in order, without delivering Alice's again.
+	n, err = consumer.Poll(ctx)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 delivered events, got %d", n)
+	}
+	if _, err := client.Counterparties.Update(ctx, alice.ID, moderntreasury.CounterpartyUpdateParams{Name: moderntreasury.F("Alicia")}); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if n, err = consumer.Poll(ctx); err != nil || n != 1 {
+		t.Fatalf("expected 1 delivered event, got %d, %v", n, err)
+	}
+
+	want := []string{"created Alice", "created Bob", "updated Bobby", "updated Alicia"}
+	if len(names) != len(want) {
+		t.Fatalf("expected %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, names)
+		}
+	}
+
+	checkpoint, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if checkpoint == nil || len(checkpoint.Delivered) != 4 {
+		t.Fatalf("expected a checkpoint with the 4 delivered events, got %+v", checkpoint)
+	}
+}
+
+// dispatcherFunc adapts a function to a moderntreasury.EventDispatcher.
+type dispatcherFunc func(ctx context.Context, event moderntreasury.WebhookEvent) error
+
+func (f dispatcherFunc) Dispatch(ctx context.Context, event moderntreasury.WebhookEvent) error {
+	return f(ctx, event)
+}
+
+func TestEventConsumerBatches(t *testing.T) {
+	srv := mtfake.NewServer()
+	defer srv.Close()
+	start := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+	for i := 0; i < 20; i++ {
+		srv.Add("events", mtfake.Object{
+			"resource":   "counterparty",
+			"event_name": "created",
+			"entity_id":  fmt.Sprintf("cp_%d", i),
+			"event_time": start.Add(time.Duration(i) * 10 * time.Minute).Format(time.RFC3339),
+			"data":       mtfake.Object{},
+		})
+	}
+	var requests int64
+	client := moderntreasury.NewClient(append(srv.Options(), option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		atomic.AddInt64(&requests, 1)
+		return next(req)
+	}))...)
+
+	// The events of the 3 hours are listed in windows of at most 4 events.
+	var ids []string
+	store := moderntreasury.NewMemoryEventCheckpointStore()
+	consumer := client.Events.NewConsumer(dispatcherFunc(func(ctx context.Context, event moderntreasury.WebhookEvent) error {
+		ids = append(ids, event.ID)
+		return nil
+	}), moderntreasury.EventConsumerOptions{
+		Store:     store,
+		Start:     start,
+		Overlap:   time.Second,
+		Window:    2 * time.Hour,
+		BatchSize: 4,
+	}, option.WithQuery("per_page", "2"))
+	n, err := consumer.Poll(context.Background())
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if n != 20 || len(ids) != 20 {
+		t.Fatalf("expected 20 delivered events, got %d", n)
+	}
+	checkpoint, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if checkpoint.EventTime.Before(start.Add(190 * time.Minute)) {
+		t.Fatalf("expected the checkpoint to advance past the events, got %s", checkpoint.EventTime)
+	}
+	if len(checkpoint.Delivered) > 1 {
+		t.Fatalf("expected only the IDs within the overlap to be kept, got %d", len(checkpoint.Delivered))
+	}
+}
+
+func TestEventConsumerDeadLetter(t *testing.T) {
+	srv := mtfake.NewServer()
+	defer srv.Close()
+	client := moderntreasury.NewClient(srv.Options()...)
+	ctx := context.Background()
+
+	var delivered []string
+	var dead []string
+	store := moderntreasury.NewMemoryEventCheckpointStore()
+	consumer := client.Events.NewConsumer(dispatcherFunc(func(ctx context.Context, event moderntreasury.WebhookEvent) error {
+		if event.Event == "created" {
+			return errors.New("invalid counterparty")
+		}
+		delivered = append(delivered, event.Event)
+		return nil
+	}), moderntreasury.EventConsumerOptions{
+		Store:       store,
+		Start:       time.Now().Add(-time.Minute),
+		MaxAttempts: 2,
+		DeadLetter: func(ctx context.Context, event moderntreasury.Event, err error) error {
+			dead = append(dead, event.EventName)
+			return nil
+		},
+	})
+
+	counterparty, err := client.Counterparties.New(ctx, moderntreasury.CounterpartyNewParams{Name: moderntreasury.F("Alice")})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if _, err := client.Counterparties.Update(ctx, counterparty.ID, moderntreasury.CounterpartyUpdateParams{Name: moderntreasury.F("Alicia")}); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+
+	// The creation fails twice and is given up on, which releases the update.
+	if n, err := consumer.Poll(ctx); err == nil || n != 0 {
+		t.Fatalf("expected the first attempt to fail, got %d, %v", n, err)
+	}
+	if n, err := consumer.Poll(ctx); err != nil || n != 1 {
+		t.Fatalf("expected the update to be delivered, got %d, %v", n, err)
+	}
+	if len(dead) != 1 || dead[0] != "created" || len(delivered) != 1 || delivered[0] != "updated" {
+		t.Fatalf("expected the creation to be dead-lettered and the update delivered, got %v, %v", dead, delivered)
+	}
+	checkpoint, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if len(checkpoint.Attempts) != 0 {
+		t.Fatalf("expected no attempts to be kept, got %v", checkpoint.Attempts)
+	}
+	if n, err := consumer.Poll(ctx); err != nil || n != 0 {
+		t.Fatalf("expected nothing to be delivered again, got %d, %v", n, err)
+	}
+}
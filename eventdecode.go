@@ -0,0 +1,173 @@
+package moderntreasury
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownEventResource is returned when the data of an event or webhook is
+// decoded, but no type is registered for its resource.
+var ErrUnknownEventResource = errors.New("no type is registered for the resource of the event")
+
+// EventDecoder decodes the data of an event or webhook into a value of the type
+// of its resource.
+type EventDecoder func(data []byte) (interface{}, error)
+
+type eventDecoderKey struct {
+	resource  string
+	eventName string
+}
+
+var eventDecoders = struct {
+	mu       sync.RWMutex
+	decoders map[eventDecoderKey]EventDecoder
+}{decoders: map[eventDecoderKey]EventDecoder{}}
+
+// RegisterEventDecoder registers the decoder for the data of events and webhooks
+// of the resource, e.g. for resources which the SDK doesn't know yet. If
+// eventName isn't empty, the decoder only applies to events of that name, and
+// takes precedence over the decoder of the resource. Registering a decoder
+// replaces any decoder which was registered for the same resource and event name.
+func RegisterEventDecoder(resource string, eventName string, decoder EventDecoder) {
+	eventDecoders.mu.Lock()
+	defer eventDecoders.mu.Unlock()
+	eventDecoders.decoders[eventDecoderKey{resource, eventName}] = decoder
+}
+
+// RegisterEventType registers T as the type of the data of events and webhooks of
+// the resource, which is decoded into a *T.
+func RegisterEventType[T any](resource string) {
+	RegisterEventDecoder(resource, "", func(data []byte) (interface{}, error) {
+		res := new(T)
+		if err := json.Unmarshal(data, res); err != nil {
+			return nil, err
+		}
+		return res, nil
+	})
+}
+
+func init() {
+	RegisterEventType[AccountCollectionFlow]("account_collection_flow")
+	RegisterEventType[Connection]("connection")
+	RegisterEventType[Counterparty]("counterparty")
+	RegisterEventType[Document]("document")
+	RegisterEventType[ExpectedPayment]("expected_payment")
+	RegisterEventType[ExternalAccount]("external_account")
+	RegisterEventType[IncomingPaymentDetail]("incoming_payment_detail")
+	RegisterEventType[InternalAccount]("internal_account")
+	RegisterEventType[Invoice]("invoice")
+	RegisterEventType[LedgerAccountBalanceMonitor]("ledger_account_balance_monitor")
+	RegisterEventType[LedgerAccountPayout]("ledger_account_payout")
+	RegisterEventType[LedgerTransaction]("ledger_transaction")
+	RegisterEventType[PaperItem]("paper_item")
+	RegisterEventType[PaymentFlow]("payment_flow")
+	RegisterEventType[PaymentOrder]("payment_order")
+	RegisterEventType[ReturnObject]("return")
+	RegisterEventType[Reversal]("reversal")
+	RegisterEventType[Transaction]("transaction")
+	RegisterEventType[VirtualAccount]("virtual_account")
+}
+
+// decodeEventData decodes the data with the decoder registered for the resource
+// and event name.
+func decodeEventData(resource string, eventName string, data []byte) (interface{}, error) {
+	eventDecoders.mu.RLock()
+	decoder, ok := eventDecoders.decoders[eventDecoderKey{resource, eventName}]
+	if !ok {
+		decoder, ok = eventDecoders.decoders[eventDecoderKey{resource, ""}]
+	}
+	eventDecoders.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEventResource, resource)
+	}
+	return decoder(data)
+}
+
+// decodeEventAs decodes the data into a *T, which must be the type registered
+// for the resource.
+func decodeEventAs[T any](resource string, eventName string, data []byte) (*T, error) {
+	v, err := decodeEventData(resource, eventName, data)
+	if err != nil {
+		return nil, err
+	}
+	res, ok := v.(*T)
+	if !ok {
+		return nil, fmt.Errorf("the data of the %q event is a %T, not a %T", resource, v, res)
+	}
+	return res, nil
+}
+
+// data returns the data of the event as JSON.
+func (r *Event) data() []byte {
+	if raw := r.JSON.Data.Raw(); raw != "" {
+		return []byte(raw)
+	}
+	data, _ := json.Marshal(r.Data)
+	return data
+}
+
+// Decode decodes the data of the event into the type registered for its
+// resource, e.g. a *PaymentOrder for "payment_order" events. It returns
+// [ErrUnknownEventResource] for resources without a registered type, see
+// [RegisterEventDecoder].
+func (r *Event) Decode() (interface{}, error) {
+	return decodeEventData(r.Resource, r.EventName, r.data())
+}
+
+// AsPaymentOrder decodes the data of a "payment_order" event.
+func (r *Event) AsPaymentOrder() (*PaymentOrder, error) {
+	return decodeEventAs[PaymentOrder](r.Resource, r.EventName, r.data())
+}
+
+// AsExpectedPayment decodes the data of an "expected_payment" event.
+func (r *Event) AsExpectedPayment() (*ExpectedPayment, error) {
+	return decodeEventAs[ExpectedPayment](r.Resource, r.EventName, r.data())
+}
+
+// AsReturn decodes the data of a "return" event.
+func (r *Event) AsReturn() (*ReturnObject, error) {
+	return decodeEventAs[ReturnObject](r.Resource, r.EventName, r.data())
+}
+
+// AsIncomingPaymentDetail decodes the data of an "incoming_payment_detail" event.
+func (r *Event) AsIncomingPaymentDetail() (*IncomingPaymentDetail, error) {
+	return decodeEventAs[IncomingPaymentDetail](r.Resource, r.EventName, r.data())
+}
+
+// AsLedgerTransaction decodes the data of a "ledger_transaction" event.
+func (r *Event) AsLedgerTransaction() (*LedgerTransaction, error) {
+	return decodeEventAs[LedgerTransaction](r.Resource, r.EventName, r.data())
+}
+
+// AsTransaction decodes the data of a "transaction" event.
+func (r *Event) AsTransaction() (*Transaction, error) {
+	return decodeEventAs[Transaction](r.Resource, r.EventName, r.data())
+}
+
+// AsCounterparty decodes the data of a "counterparty" event.
+func (r *Event) AsCounterparty() (*Counterparty, error) {
+	return decodeEventAs[Counterparty](r.Resource, r.EventName, r.data())
+}
+
+// AsExternalAccount decodes the data of an "external_account" event.
+func (r *Event) AsExternalAccount() (*ExternalAccount, error) {
+	return decodeEventAs[ExternalAccount](r.Resource, r.EventName, r.data())
+}
+
+// AsPaperItem decodes the data of a "paper_item" event.
+func (r *Event) AsPaperItem() (*PaperItem, error) {
+	return decodeEventAs[PaperItem](r.Resource, r.EventName, r.data())
+}
+
+// AsReversal decodes the data of a "reversal" event.
+func (r *Event) AsReversal() (*Reversal, error) {
+	return decodeEventAs[Reversal](r.Resource, r.EventName, r.data())
+}
+
+// Decode decodes the data of the webhook into the type registered for its topic,
+// like [Event.Decode].
+func (e WebhookEvent) Decode() (interface{}, error) {
+	return decodeEventData(e.Topic, e.Event, e.Data)
+}
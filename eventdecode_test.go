@@ -0,0 +1,57 @@
+package moderntreasury_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+)
+
+func TestEventDecode(t *testing.T) {
+	var event moderntreasury.Event
+	err := json.Unmarshal([]byte(`{
+		"id": "evt_123",
+		"resource": "payment_order",
+		"event_name": "completed",
+		"data": {"id": "po_123", "amount": 1000, "status": "completed"}
+	}`), &event)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+
+	decoded, err := event.Decode()
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	paymentOrder, ok := decoded.(*moderntreasury.PaymentOrder)
+	if !ok || paymentOrder.ID != "po_123" || paymentOrder.Amount != 1000 || paymentOrder.Status != moderntreasury.PaymentOrderStatusCompleted {
+		t.Fatalf("unexpected decoded data: %#v", decoded)
+	}
+	if _, err := event.AsExpectedPayment(); err == nil {
+		t.Fatalf("expected an error decoding a payment order event as an expected payment")
+	}
+
+	// Events built in code, without their raw JSON, are decoded too.
+	built := moderntreasury.Event{Resource: "payment_order", Data: map[string]interface{}{"id": "po_456"}}
+	if paymentOrder, err := built.AsPaymentOrder(); err != nil || paymentOrder.ID != "po_456" {
+		t.Fatalf("unexpected payment order: %+v, %v", paymentOrder, err)
+	}
+
+	unknown := moderntreasury.Event{Resource: "widget", EventName: "created", Data: map[string]interface{}{"name": "w"}}
+	if _, err := unknown.Decode(); !errors.Is(err, moderntreasury.ErrUnknownEventResource) {
+		t.Fatalf("expected ErrUnknownEventResource, got %v", err)
+	}
+
+	type widget struct {
+		Name string `json:"name"`
+	}
+	moderntreasury.RegisterEventType[widget]("widget")
+	decoded, err = unknown.Decode()
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if w, ok := decoded.(*widget); !ok || w.Name != "w" {
+		t.Fatalf("unexpected decoded data: %#v", decoded)
+	}
+}
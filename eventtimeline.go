@@ -0,0 +1,173 @@
+package moderntreasury
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+)
+
+// Timeline is the history of one object, reconstructed from its events.
+type Timeline struct {
+	// The ID of the object.
+	EntityID string `json:"entity_id"`
+	// The type of the object, e.g. "payment_order".
+	Resource string `json:"resource"`
+	// The events of the object, from the oldest to the newest.
+	Entries []TimelineEntry `json:"entries"`
+}
+
+// TimelineEntry is an event of a [Timeline], with the fields of the object which
+// it changed. The Events API doesn't record who caused an event, so the changes
+// are attributed to the event itself.
+type TimelineEntry struct {
+	EventID   string    `json:"event_id"`
+	EventName string    `json:"event_name"`
+	EventTime time.Time `json:"event_time"`
+	LiveMode  bool      `json:"live_mode"`
+	// The fields which changed since the previous event, sorted by path. The
+	// first event lists every field of the object as changed from nil.
+	Changes []FieldChange `json:"changes"`
+}
+
+// FieldChange is a change of a field of an object.
+type FieldChange struct {
+	// The path of the field, e.g. "status", "metadata.invoice_id" or
+	// "reference_numbers[0].reference_number".
+	Path string `json:"path"`
+	// The value before the change, or nil if the field was added.
+	Before interface{} `json:"before"`
+	// The value after the change, or nil if the field was removed.
+	After interface{} `json:"after"`
+}
+
+// Timeline lists the events of the entity and reconstructs its history: each of
+// its events, in order of their time, with the fields of the entity which
+// changed since the previous event.
+func (r *EventService) Timeline(ctx context.Context, entityID string, opts ...option.RequestOption) (*Timeline, error) {
+	iter := r.ListAutoPaging(ctx, EventListParams{EntityID: F(entityID)}, opts...)
+	var events []Event
+	for iter.Next() {
+		events = append(events, iter.Current())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].EventTime.Before(events[j].EventTime)
+	})
+
+	timeline := &Timeline{EntityID: entityID, Entries: []TimelineEntry{}}
+	var previous interface{} = map[string]interface{}{}
+	for _, event := range events {
+		if timeline.Resource == "" {
+			timeline.Resource = event.Resource
+		}
+		var snapshot interface{}
+		decoder := json.NewDecoder(bytes.NewReader(event.data()))
+		decoder.UseNumber()
+		if err := decoder.Decode(&snapshot); err != nil {
+			return nil, fmt.Errorf("invalid data of event %s: %w", event.ID, err)
+		}
+		if snapshot == nil {
+			snapshot = map[string]interface{}{}
+		}
+		changes := []FieldChange{}
+		diffFields("", previous, snapshot, &changes)
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+		timeline.Entries = append(timeline.Entries, TimelineEntry{
+			EventID:   event.ID,
+			EventName: event.EventName,
+			EventTime: event.EventTime,
+			LiveMode:  event.LiveMode,
+			Changes:   changes,
+		})
+		previous = snapshot
+	}
+	return timeline, nil
+}
+
+// diffFields appends the changes between the values at the path.
+func diffFields(path string, before interface{}, after interface{}, changes *[]FieldChange) {
+	switch b := before.(type) {
+	case map[string]interface{}:
+		if a, ok := after.(map[string]interface{}); ok {
+			for key, value := range b {
+				diffFields(joinPath(path, key), value, a[key], changes)
+			}
+			for key, value := range a {
+				if _, ok := b[key]; !ok {
+					diffFields(joinPath(path, key), nil, value, changes)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if a, ok := after.([]interface{}); ok {
+			for i := 0; i < len(b) || i < len(a); i++ {
+				var before, after interface{}
+				if i < len(b) {
+					before = b[i]
+				}
+				if i < len(a) {
+					after = a[i]
+				}
+				diffFields(fmt.Sprintf("%s[%d]", path, i), before, after, changes)
+			}
+			return
+		}
+	case nil:
+		// Fields which are added as objects list each of their fields, so that the
+		// first event of a timeline lists every field.
+		if a, ok := after.(map[string]interface{}); ok && path != "" {
+			diffFields(path, map[string]interface{}{}, a, changes)
+			if len(a) > 0 {
+				return
+			}
+		}
+	}
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, FieldChange{Path: path, Before: before, After: after})
+	}
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// String renders the timeline as text, e.g. for a compliance ticket:
+//
+//	payment_order 6f2a...
+//	2023-05-01T12:00:00Z  completed  (event 9c1b...)
+//	  status: "processing" -> "completed"
+func (t *Timeline) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", t.Resource, t.EntityID)
+	for _, entry := range t.Entries {
+		fmt.Fprintf(&b, "%s  %s  (event %s)\n", entry.EventTime.UTC().Format(time.RFC3339), entry.EventName, entry.EventID)
+		for _, change := range entry.Changes {
+			fmt.Fprintf(&b, "  %s: %s -> %s\n", change.Path, renderValue(change.Before), renderValue(change.After))
+		}
+	}
+	return b.String()
+}
+
+func renderValue(value interface{}) string {
+	if number, ok := value.(json.Number); ok {
+		return number.String()
+	}
+	contents, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(contents)
+}
@@ -0,0 +1,68 @@
+package moderntreasury_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+	"github.com/Modern-Treasury/modern-treasury-go/testutil/mtfake"
+)
+
+func TestEventTimeline(t *testing.T) {
+	srv := mtfake.NewServer()
+	defer srv.Close()
+	client := moderntreasury.NewClient(srv.Options()...)
+	ctx := context.Background()
+
+	counterparty, err := client.Counterparties.New(ctx, moderntreasury.CounterpartyNewParams{
+		Name:     moderntreasury.F("Alice"),
+		Metadata: moderntreasury.F(map[string]string{"team": "payments"}),
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if _, err := client.Counterparties.New(ctx, moderntreasury.CounterpartyNewParams{Name: moderntreasury.F("Bob")}); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	_, err = client.Counterparties.Update(ctx, counterparty.ID, moderntreasury.CounterpartyUpdateParams{
+		Name:     moderntreasury.F("Alicia"),
+		Metadata: moderntreasury.F(map[string]string{"team": "treasury"}),
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+
+	timeline, err := client.Events.Timeline(ctx, counterparty.ID)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if timeline.Resource != "counterparty" || len(timeline.Entries) != 2 {
+		t.Fatalf("expected 2 entries of a counterparty, got %+v", timeline)
+	}
+	if timeline.Entries[0].EventName != "created" || len(timeline.Entries[0].Changes) == 0 {
+		t.Fatalf("expected the creation to list the fields of the counterparty, got %+v", timeline.Entries[0])
+	}
+
+	changes := map[string]moderntreasury.FieldChange{}
+	for _, change := range timeline.Entries[1].Changes {
+		changes[change.Path] = change
+	}
+	if name := changes["name"]; name.Before != "Alice" || name.After != "Alicia" {
+		t.Fatalf("expected the name to change, got %+v", timeline.Entries[1].Changes)
+	}
+	if team := changes["metadata.team"]; team.Before != "payments" || team.After != "treasury" {
+		t.Fatalf("expected the team to change, got %+v", timeline.Entries[1].Changes)
+	}
+	if _, ok := changes["id"]; ok {
+		t.Fatalf("expected the ID not to change, got %+v", timeline.Entries[1].Changes)
+	}
+
+	if text := timeline.String(); !strings.Contains(text, `name: "Alice" -> "Alicia"`) {
+		t.Fatalf("unexpected text:\n%s", text)
+	}
+	if _, err := json.Marshal(timeline); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+}
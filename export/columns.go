@@ -0,0 +1,261 @@
+package export
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// kind is the type of the values of a column.
+type kind int
+
+const (
+	kindString kind = iota
+	kindInt
+	kindFloat
+	kindBool
+	kindTime
+	// Values which are encoded as JSON, or joined from several values.
+	kindText
+)
+
+// column is a column of an export, and the way to its values from an item.
+type column struct {
+	name  string
+	kind  kind
+	steps []step
+	// Whether the values are dates, from the `format:"date"` tag of their field.
+	date bool
+}
+
+type step struct {
+	// The index of a struct field, or -1.
+	field int
+	// The key of a map, when field is -1.
+	key string
+	// Whether the step is to each element of a slice.
+	each bool
+}
+
+// columns returns the columns of the items of the given type, derived from the
+// `json` tags of its fields.
+func columns(t reflect.Type, opts Options) []column {
+	var cols []column
+	addColumns(&cols, t, "", nil, 0, false, opts)
+	if len(opts.Columns) == 0 {
+		return cols
+	}
+	byName := map[string]column{}
+	for _, col := range cols {
+		byName[col.name] = col
+	}
+	selected := make([]column, 0, len(opts.Columns))
+	for _, name := range opts.Columns {
+		if col, ok := byName[name]; ok {
+			selected = append(selected, col)
+		}
+	}
+	return selected
+}
+
+func addColumns(cols *[]column, t reflect.Type, prefix string, steps []step, depth int, joined bool, opts Options) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := jsonName(field)
+		if name == "" {
+			continue
+		}
+		name = prefix + name
+		fieldSteps := append(append([]step(nil), steps...), step{field: i})
+		col := column{name: name, steps: fieldSteps, date: field.Tag.Get("format") == "date"}
+
+		ft := field.Type
+		switch {
+		case ft == timeType:
+			col.kind = kindTime
+		case ft.Kind() == reflect.Struct && depth < opts.Flatten:
+			addColumns(cols, ft, name+".", fieldSteps, depth+1, joined, opts)
+			continue
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Struct && ft.Elem() != timeType && depth < opts.Flatten:
+			fieldSteps[len(fieldSteps)-1].each = true
+			addColumns(cols, ft.Elem(), name+".", fieldSteps, depth+1, true, opts)
+			continue
+		case ft.Kind() == reflect.Map && len(opts.MapKeys[name]) > 0:
+			for _, key := range opts.MapKeys[name] {
+				keySteps := append(append([]step(nil), fieldSteps...), step{field: -1, key: key})
+				*cols = append(*cols, column{name: name + "." + key, kind: kindText, steps: keySteps})
+			}
+			continue
+		default:
+			col.kind = kindOf(ft)
+		}
+		if joined && col.kind != kindText {
+			col.kind = kindText
+		}
+		*cols = append(*cols, col)
+	}
+}
+
+func jsonName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func kindOf(t reflect.Type) kind {
+	switch t.Kind() {
+	case reflect.String:
+		return kindString
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return kindInt
+	case reflect.Float32, reflect.Float64:
+		return kindFloat
+	case reflect.Bool:
+		return kindBool
+	default:
+		return kindText
+	}
+}
+
+// values returns the values of the column in the item. There is more than one
+// value when the column is of the elements of a slice.
+func (c column) values(item reflect.Value) []reflect.Value {
+	values := []reflect.Value{item}
+	for _, s := range c.steps {
+		var next []reflect.Value
+		for _, v := range values {
+			switch {
+			case s.field < 0:
+				if v.IsNil() {
+					continue
+				}
+				if value := v.MapIndex(reflect.ValueOf(s.key)); value.IsValid() {
+					next = append(next, value)
+				}
+			case s.each:
+				slice := v.Field(s.field)
+				for i := 0; i < slice.Len(); i++ {
+					next = append(next, slice.Index(i))
+				}
+			default:
+				next = append(next, v.Field(s.field))
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// text returns the value of the column in the item as text, and whether it has
+// a value.
+func (c column) text(item reflect.Value, separator string) (string, bool) {
+	values := c.values(item)
+	if len(values) == 0 {
+		return "", false
+	}
+	texts := make([]string, 0, len(values))
+	for _, v := range values {
+		if text, ok := c.format(v); ok {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, separator), len(texts) > 0
+}
+
+func (c column) format(v reflect.Value) (string, bool) {
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return "", false
+		}
+		if c.date {
+			return t.Format("2006-01-02"), true
+		}
+		return t.Format(time.RFC3339Nano), true
+	}
+	switch kindOf(v.Type()) {
+	case kindString:
+		return v.String(), true
+	case kindInt:
+		if v.CanInt() {
+			return strconv.FormatInt(v.Int(), 10), true
+		}
+		return strconv.FormatUint(v.Uint(), 10), true
+	case kindFloat:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), true
+	case kindBool:
+		return strconv.FormatBool(v.Bool()), true
+	}
+	if (v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.IsNil() {
+		return "", false
+	}
+	encoded, err := json.Marshal(plain(v))
+	if err != nil {
+		return "", false
+	}
+	return string(encoded), true
+}
+
+// plain returns the value as maps, slices and scalars keyed by the `json` tags of
+// its fields, for encoding as JSON.
+func plain(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return nil
+		}
+		return t
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		object := map[string]interface{}{}
+		for i := 0; i < v.NumField(); i++ {
+			if name := jsonName(v.Type().Field(i)); name != "" {
+				object[name] = plain(v.Field(i))
+			}
+		}
+		return object
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		object := map[string]interface{}{}
+		for _, key := range v.MapKeys() {
+			object[key.String()] = plain(v.MapIndex(key))
+		}
+		return object
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+		elements := make([]interface{}, v.Len())
+		for i := range elements {
+			elements[i] = plain(v.Index(i))
+		}
+		return elements
+	default:
+		return v.Interface()
+	}
+}
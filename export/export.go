@@ -0,0 +1,166 @@
+// Package export streams the items of any list of the Modern Treasury API to
+// NDJSON, CSV or Parquet, e.g. for weekly spreadsheet dumps of payment orders,
+// transactions or ledger entries:
+//
+//	iter := client.PaymentOrders.ListAutoPaging(ctx, moderntreasury.PaymentOrderListParams{})
+//	n, err := export.Write(w, export.FormatCSV, iter, export.Options{
+//		Flatten: 1,
+//		MapKeys: map[string][]string{"metadata": {"invoice_id"}},
+//	})
+//
+// Items are written as they are listed, so memory use doesn't grow with the size
+// of the list. The columns of CSV and Parquet exports are derived from the `json`
+// tags of the items' fields.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+)
+
+// Format is the file format of an export.
+type Format string
+
+const (
+	// FormatNDJSON writes each item as a JSON object on its own line, with its
+	// nested objects as they are. The Options which select and flatten columns
+	// don't apply.
+	FormatNDJSON Format = "ndjson"
+	// FormatCSV writes a header row with the names of the columns, and then a row
+	// for each item.
+	FormatCSV Format = "csv"
+	// FormatParquet writes a Parquet file with a column for each column of the
+	// export, typed after the fields they are derived from.
+	FormatParquet Format = "parquet"
+)
+
+// Options configures the columns of an export.
+type Options struct {
+	// The number of levels of nested objects which are flattened into columns of
+	// their own, named by their path, e.g. `accounting.account_id`. Lists of
+	// objects, e.g. `reference_numbers`, are flattened into a column for each of
+	// their fields, which joins the values of the objects with Separator. Objects
+	// and lists which are not flattened are written as JSON. Defaults to 0.
+	Flatten int
+	// The keys of maps which are written as columns of their own, by the name of
+	// the map's column, e.g. `{"metadata": {"invoice_id"}}` for a
+	// `metadata.invoice_id` column. Maps which are not listed are written as JSON.
+	MapKeys map[string][]string
+	// If set, the names of the columns to write, in order. Otherwise all columns
+	// are written, in the order of the fields of the items.
+	Columns []string
+	// The separator of values joined from lists. Defaults to "; ".
+	Separator string
+	// The number of rows of each row group of Parquet exports, which are buffered
+	// in memory until they are written. Defaults to 10,000.
+	RowGroupSize int
+}
+
+// Write writes the items of the pager to w in the given format, and returns the
+// number of items written. It stops at the first error from the pager or w.
+func Write[T any](w io.Writer, format Format, pager moderntreasury.Pager[T], opts Options) (int, error) {
+	if opts.Separator == "" {
+		opts.Separator = "; "
+	}
+	if opts.RowGroupSize <= 0 {
+		opts.RowGroupSize = 10000
+	}
+
+	var rw rowWriter
+	switch format {
+	case FormatNDJSON:
+		rw = newNDJSONWriter(w)
+	case FormatCSV:
+		rw = newCSVWriter(w, columns(reflect.TypeOf((*T)(nil)).Elem(), opts), opts)
+	case FormatParquet:
+		rw = newParquetWriter(w, columns(reflect.TypeOf((*T)(nil)).Elem(), opts), opts)
+	default:
+		return 0, fmt.Errorf("export: unknown format %q", format)
+	}
+
+	n := 0
+	for pager.Next() {
+		item := pager.Current()
+		if err := rw.write(reflect.ValueOf(&item).Elem()); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := pager.Err(); err != nil {
+		return n, err
+	}
+	return n, rw.close()
+}
+
+// rowWriter writes the items of an export in its format.
+type rowWriter interface {
+	write(item reflect.Value) error
+	// close writes whatever is buffered, but doesn't close the underlying writer.
+	close() error
+}
+
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	encoder *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	buffered := bufio.NewWriter(w)
+	return &ndjsonWriter{w: buffered, encoder: json.NewEncoder(buffered)}
+}
+
+func (w *ndjsonWriter) write(item reflect.Value) error {
+	return w.encoder.Encode(plain(item))
+}
+
+func (w *ndjsonWriter) close() error {
+	return w.w.Flush()
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []column
+	opts    Options
+	record  []string
+	header  bool
+}
+
+func newCSVWriter(w io.Writer, columns []column, opts Options) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w), columns: columns, opts: opts, record: make([]string, len(columns))}
+}
+
+func (w *csvWriter) write(item reflect.Value) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	for i, col := range w.columns {
+		w.record[i], _ = col.text(item, w.opts.Separator)
+	}
+	return w.w.Write(w.record)
+}
+
+func (w *csvWriter) writeHeader() error {
+	if w.header {
+		return nil
+	}
+	w.header = true
+	for i, col := range w.columns {
+		w.record[i] = col.name
+	}
+	return w.w.Write(w.record)
+}
+
+func (w *csvWriter) close() error {
+	// An empty export still has a header row.
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
@@ -0,0 +1,124 @@
+package export_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+	"github.com/Modern-Treasury/modern-treasury-go/export"
+	"github.com/Modern-Treasury/modern-treasury-go/testutil/mtfake"
+	"github.com/parquet-go/parquet-go"
+)
+
+func newPaymentOrders() (*moderntreasury.Client, func()) {
+	srv := mtfake.NewServer()
+	for i := 0; i < 3; i++ {
+		srv.Add("payment_orders", mtfake.Object{
+			"amount":         1000 * (i + 1),
+			"direction":      "credit",
+			"effective_date": "2023-05-01",
+			"accounting":     mtfake.Object{"account_id": "acct_123"},
+			"metadata":       mtfake.Object{"invoice_id": "inv_123"},
+			"reference_numbers": []interface{}{
+				mtfake.Object{"reference_number": "ref_a"},
+				mtfake.Object{"reference_number": "ref_b"},
+			},
+		})
+	}
+	return moderntreasury.NewClient(srv.Options()...), srv.Close
+}
+
+func TestCSV(t *testing.T) {
+	client, closeServer := newPaymentOrders()
+	defer closeServer()
+
+	var buf bytes.Buffer
+	iter := client.PaymentOrders.ListAutoPaging(context.Background(), moderntreasury.PaymentOrderListParams{})
+	n, err := export.Write(&buf, export.FormatCSV, iter, export.Options{
+		Flatten: 1,
+		MapKeys: map[string][]string{"metadata": {"invoice_id"}},
+		Columns: []string{"id", "amount", "effective_date", "accounting.account_id", "metadata.invoice_id", "reference_numbers.reference_number"},
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 rows, got %d", n)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if strings.Join(records[0], ",") != "id,amount,effective_date,accounting.account_id,metadata.invoice_id,reference_numbers.reference_number" {
+		t.Fatalf("unexpected header: %v", records[0])
+	}
+	if len(records) != 4 || records[1][1] != "1000" || records[1][2] != "2023-05-01" || records[1][3] != "acct_123" ||
+		records[1][4] != "inv_123" || records[1][5] != "ref_a; ref_b" {
+		t.Fatalf("unexpected records: %v", records)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	client, closeServer := newPaymentOrders()
+	defer closeServer()
+
+	var buf bytes.Buffer
+	iter := client.PaymentOrders.ListAutoPaging(context.Background(), moderntreasury.PaymentOrderListParams{})
+	if _, err := export.Write(&buf, export.FormatNDJSON, iter, export.Options{}); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(lines))
+	}
+	var row struct {
+		Amount           int64             `json:"amount"`
+		Metadata         map[string]string `json:"metadata"`
+		ReferenceNumbers []struct {
+			ReferenceNumber string `json:"reference_number"`
+		} `json:"reference_numbers"`
+	}
+	if err := json.Unmarshal([]byte(lines[2]), &row); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if row.Amount != 3000 || row.Metadata["invoice_id"] != "inv_123" || len(row.ReferenceNumbers) != 2 {
+		t.Fatalf("unexpected row: %+v", row)
+	}
+}
+
+func TestParquet(t *testing.T) {
+	client, closeServer := newPaymentOrders()
+	defer closeServer()
+
+	var buf bytes.Buffer
+	iter := client.PaymentOrders.ListAutoPaging(context.Background(), moderntreasury.PaymentOrderListParams{})
+	if _, err := export.Write(&buf, export.FormatParquet, iter, export.Options{Flatten: 1}); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+
+	file, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if file.NumRows() != 3 {
+		t.Fatalf("expected 3 rows, got %d", file.NumRows())
+	}
+	if _, ok := file.Schema().Lookup("accounting.account_id"); !ok {
+		t.Fatalf("expected a flattened accounting.account_id column, got %v", file.Schema().Columns())
+	}
+
+	rows := make([]parquet.Row, 3)
+	reader := parquet.NewReader(bytes.NewReader(buf.Bytes()))
+	if n, _ := reader.ReadRows(rows); n != 3 {
+		t.Fatalf("expected to read 3 rows, read %d", n)
+	}
+	amount, _ := file.Schema().Lookup("amount")
+	if rows[1][amount.ColumnIndex].Int64() != 2000 {
+		t.Fatalf("expected the amount of the second row to be 2000, got %v", rows[1][amount.ColumnIndex])
+	}
+}
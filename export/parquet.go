@@ -0,0 +1,127 @@
+package export
+
+import (
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+type parquetWriter struct {
+	w       *parquet.Writer
+	columns []column
+	// The index of each column in the Parquet schema, which orders its columns by
+	// name.
+	indexes []int
+	opts    Options
+	rows    []parquet.Row
+}
+
+func newParquetWriter(w io.Writer, columns []column, opts Options) *parquetWriter {
+	group := parquet.Group{}
+	for _, col := range columns {
+		group[col.name] = parquet.Optional(parquetNode(col))
+	}
+	schema := parquet.NewSchema("row", group)
+	indexes := make([]int, len(columns))
+	for i, col := range columns {
+		leaf, _ := schema.Lookup(col.name)
+		indexes[i] = leaf.ColumnIndex
+	}
+	return &parquetWriter{
+		w:       parquet.NewWriter(w, schema, parquet.MaxRowsPerRowGroup(int64(opts.RowGroupSize))),
+		columns: columns,
+		indexes: indexes,
+		opts:    opts,
+	}
+}
+
+func parquetNode(col column) parquet.Node {
+	switch col.kind {
+	case kindInt:
+		return parquet.Int(64)
+	case kindFloat:
+		return parquet.Leaf(parquet.DoubleType)
+	case kindBool:
+		return parquet.Leaf(parquet.BooleanType)
+	case kindTime:
+		if col.date {
+			return parquet.Date()
+		}
+		return parquet.Timestamp(parquet.Millisecond)
+	default:
+		return parquet.String()
+	}
+}
+
+func (w *parquetWriter) write(item reflect.Value) error {
+	row := make(parquet.Row, len(w.columns))
+	for i, col := range w.columns {
+		value := w.value(col, item)
+		if value.IsNull() {
+			row[w.indexes[i]] = value.Level(0, 0, w.indexes[i])
+		} else {
+			row[w.indexes[i]] = value.Level(0, 1, w.indexes[i])
+		}
+	}
+	// Rows are written in batches, which the writer buffers until its row group
+	// is full.
+	w.rows = append(w.rows, row)
+	if len(w.rows) < 100 {
+		return nil
+	}
+	return w.flush()
+}
+
+func (w *parquetWriter) value(col column, item reflect.Value) parquet.Value {
+	if col.kind == kindText || col.kind == kindString {
+		text, ok := col.text(item, w.opts.Separator)
+		if !ok {
+			return parquet.NullValue()
+		}
+		return parquet.ByteArrayValue([]byte(text))
+	}
+	values := col.values(item)
+	if len(values) != 1 {
+		return parquet.NullValue()
+	}
+	v := values[0]
+	switch col.kind {
+	case kindInt:
+		if v.CanInt() {
+			return parquet.Int64Value(v.Int())
+		}
+		return parquet.Int64Value(int64(v.Uint()))
+	case kindFloat:
+		return parquet.DoubleValue(v.Float())
+	case kindBool:
+		return parquet.BooleanValue(v.Bool())
+	case kindTime:
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return parquet.NullValue()
+		}
+		if col.date {
+			y, m, d := t.Date()
+			return parquet.Int32Value(int32(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400))
+		}
+		return parquet.Int64Value(t.UnixMilli())
+	}
+	return parquet.NullValue()
+}
+
+func (w *parquetWriter) flush() error {
+	if _, err := w.w.WriteRows(w.rows); err != nil {
+		return err
+	}
+	w.rows = w.rows[:0]
+	return nil
+}
+
+func (w *parquetWriter) close() error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	return w.w.Close()
+}
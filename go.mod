@@ -3,9 +3,12 @@ module github.com/Modern-Treasury/modern-treasury-go
 go 1.19
 
 require (
-	github.com/google/uuid v1.3.0 // indirect
-	github.com/tidwall/gjson v1.14.4 // indirect
+	github.com/google/uuid v1.3.0
+	github.com/tidwall/gjson v1.14.4
+	github.com/tidwall/sjson v1.2.5
+)
+
+require (
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect
-	github.com/tidwall/sjson v1.2.5 // indirect
 )
@@ -4,7 +4,6 @@ package apierror
 
 import (
 	"fmt"
-	"io"
 	"net/http"
 	"net/http/httputil"
 
@@ -15,14 +14,19 @@ import (
 // made and the API returns a response with a HTTP status code. Other errors are
 // not wrapped by this SDK.
 type Error struct {
+	// The error reported in the response body, if it was in the format of the
+	// Modern Treasury API.
+	Errors     ErrorErrors `json:"errors"`
 	JSON       errorJSON
 	StatusCode int
 	Request    *http.Request
 	Response   *http.Response
+	body       []byte
 }
 
 // errorJSON contains the JSON metadata for the struct [Error]
 type errorJSON struct {
+	Errors      apijson.Field
 	raw         string
 	ExtraFields map[string]apijson.Field
 }
@@ -31,16 +35,54 @@ func (r *Error) UnmarshalJSON(data []byte) (err error) {
 	return apijson.UnmarshalRoot(data, r)
 }
 
+type ErrorErrors struct {
+	// A machine readable code for the error, e.g. "parameter_invalid".
+	Code string `json:"code"`
+	// A human readable description of the error.
+	Message string `json:"message"`
+	// The request parameter which caused the error, if any.
+	Parameter string `json:"parameter"`
+	JSON      errorErrorsJSON
+}
+
+// errorErrorsJSON contains the JSON metadata for the struct [ErrorErrors]
+type errorErrorsJSON struct {
+	Code        apijson.Field
+	Message     apijson.Field
+	Parameter   apijson.Field
+	raw         string
+	ExtraFields map[string]apijson.Field
+}
+
+func (r *ErrorErrors) UnmarshalJSON(data []byte) (err error) {
+	return apijson.UnmarshalRoot(data, r)
+}
+
+// New returns the Error for a response with the given body, which has already
+// been read from the response. The body is parsed if it is JSON, and is kept so
+// that [Error.Error] can be called any number of times.
+func New(req *http.Request, res *http.Response, body []byte) *Error {
+	r := &Error{Request: req, Response: res, StatusCode: res.StatusCode, body: body}
+	// Bodies which are not JSON, e.g. from a proxy, are only kept as is.
+	_ = r.UnmarshalJSON(body)
+	return r
+}
+
 func (r *Error) Error() string {
-	body, _ := io.ReadAll(r.Response.Body)
-	return fmt.Sprintf("%s \"%s\": %d %s %s", r.Request.Method, r.Request.URL, r.Response.StatusCode, http.StatusText(r.Response.StatusCode), string(body))
+	return fmt.Sprintf("%s \"%s\": %d %s %s", r.Request.Method, r.Request.URL, r.StatusCode, http.StatusText(r.StatusCode), string(r.body))
 }
 
+// DumpRequest returns the serialized HTTP request, with the value of the
+// Authorization header redacted.
 func (r *Error) DumpRequest(body bool) []byte {
+	req := r.Request.Clone(r.Request.Context())
 	if r.Request.GetBody != nil {
-		r.Request.Body, _ = r.Request.GetBody()
+		req.Body, _ = r.Request.GetBody()
+	}
+	if req.Header.Get("Authorization") != "" {
+		req.Header.Set("Authorization", "[REDACTED]")
 	}
-	out, _ := httputil.DumpRequestOut(r.Request, body)
+	out, _ := httputil.DumpRequestOut(req, body)
 	return out
 }
 
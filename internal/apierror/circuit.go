@@ -0,0 +1,30 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrCircuitOpen is matched by errors.Is for requests which were rejected by a
+// circuit breaker without being sent.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitOpenError is returned for a request which was rejected by an open
+// circuit breaker without being sent. Requests which fail with this error are
+// never retried.
+type CircuitOpenError struct {
+	// The key of the circuit, which is the host of the request, optionally followed
+	// by its path template.
+	Key string
+	// When the circuit will let a trial request through.
+	RetryAt time.Time
+}
+
+func (r *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s for %q until %s", ErrCircuitOpen, r.Key, r.RetryAt.Format(time.RFC3339))
+}
+
+func (r *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
@@ -0,0 +1,55 @@
+package apierror
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RetryExhaustedError is returned when a request was retried, and its final
+// attempt failed with an error that would have been retried had there been any
+// retries, or any of the retry budget, remaining.
+//
+// It wraps the error of the final attempt, which is usually an [*Error], so the
+// errors.As pattern works as it does for requests which were not retried.
+type RetryExhaustedError struct {
+	// Attempts lists the outcome of each attempt, in the order they were made.
+	Attempts []Attempt
+}
+
+// Attempt describes the outcome of a single request attempt.
+type Attempt struct {
+	// The HTTP status code of the response, or zero if there was no response.
+	StatusCode int
+	// The `X-Request-Id` response header, if there was one.
+	RequestID string
+	// The error which caused the attempt to fail. For attempts other than the final
+	// one, this is nil when a response was received.
+	Err error
+	// How long the attempt took.
+	Duration time.Duration
+}
+
+func (r *RetryExhaustedError) Error() string {
+	history := make([]string, len(r.Attempts))
+	for i, attempt := range r.Attempts {
+		history[i] = attempt.String()
+	}
+	return fmt.Sprintf("retries exhausted after %d attempts [%s]: %s", len(r.Attempts), strings.Join(history, ", "), r.Unwrap())
+}
+
+// Unwrap returns the error of the final attempt.
+func (r *RetryExhaustedError) Unwrap() error {
+	if len(r.Attempts) == 0 {
+		return nil
+	}
+	return r.Attempts[len(r.Attempts)-1].Err
+}
+
+func (r Attempt) String() string {
+	if r.StatusCode == 0 {
+		return fmt.Sprintf("%v (%s)", r.Err, r.Duration)
+	}
+	return fmt.Sprintf("%d %s (%s)", r.StatusCode, http.StatusText(r.StatusCode), r.Duration)
+}
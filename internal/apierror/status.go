@@ -0,0 +1,50 @@
+package apierror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// IsNotFound reports whether err is, or wraps, an [*Error] with status 404 Not
+// Found.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsValidationError reports whether err is, or wraps, an [*Error] with status 400
+// Bad Request or 422 Unprocessable Entity, which the API returns for invalid
+// parameters. The offending parameter is given by [ErrorErrors.Parameter].
+func IsValidationError(err error) bool {
+	return hasStatus(err, http.StatusBadRequest, http.StatusUnprocessableEntity)
+}
+
+// IsConflict reports whether err is, or wraps, an [*Error] with status 409
+// Conflict.
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// IsRateLimited reports whether err is, or wraps, an [*Error] with status 429 Too
+// Many Requests.
+func IsRateLimited(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
+
+// IsAuth reports whether err is, or wraps, an [*Error] with status 401
+// Unauthorized or 403 Forbidden.
+func IsAuth(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized, http.StatusForbidden)
+}
+
+func hasStatus(err error, statusCodes ...int) bool {
+	var apierr *Error
+	if !errors.As(err, &apierr) {
+		return false
+	}
+	for _, code := range statusCodes {
+		if apierr.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package requestconfig
+
+import (
+	"context"
+	"sync"
+)
+
+// Call holds state shared by every attempt of a single call to
+// [RequestConfig.Execute]. It is attached to the context of each attempt, so that
+// middleware can tell attempts of the same call apart from other calls.
+type Call struct {
+	ctx    context.Context
+	mu     sync.Mutex
+	values map[interface{}]interface{}
+	done   []func(err error)
+}
+
+type callKey struct{}
+
+func withCall(ctx context.Context, call *Call) context.Context {
+	return context.WithValue(ctx, callKey{}, call)
+}
+
+// CallFromContext returns the call the context belongs to, or nil if it doesn't
+// belong to one.
+func CallFromContext(ctx context.Context) *Call {
+	call, _ := ctx.Value(callKey{}).(*Call)
+	return call
+}
+
+// Context returns the context which the call was made with. Unlike the context
+// of an attempt, it isn't done when only the attempt timed out.
+func (c *Call) Context() context.Context {
+	return c.ctx
+}
+
+// Value returns the value stored in the call for the given key.
+func (c *Call) Value(key interface{}) interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key]
+}
+
+// SetValue stores a value in the call for the given key.
+func (c *Call) SetValue(key interface{}, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.values == nil {
+		c.values = map[interface{}]interface{}{}
+	}
+	c.values[key] = value
+}
+
+// OnDone registers a function to be called with the final error of the call once
+// it is done.
+func (c *Call) OnDone(fn func(err error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.done = append(c.done, fn)
+}
+
+func (c *Call) finish(err error) {
+	c.mu.Lock()
+	done := c.done
+	c.done = nil
+	c.mu.Unlock()
+	for _, fn := range done {
+		fn(err)
+	}
+}
+
+type pageNumberKey struct{}
+
+// WithPageNumber returns a context for fetching the given page of a list, where
+// the first page is 1.
+func WithPageNumber(ctx context.Context, page int) context.Context {
+	return context.WithValue(ctx, pageNumberKey{}, page)
+}
+
+// PageNumber returns the page of a list which the context is fetching, or zero if
+// it is not known.
+func PageNumber(ctx context.Context) int {
+	page, _ := ctx.Value(pageNumberKey{}).(int)
+	return page
+}
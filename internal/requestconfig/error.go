@@ -0,0 +1,37 @@
+package requestconfig
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Modern-Treasury/modern-treasury-go/internal/apierror"
+)
+
+// withRequest wraps an error which doesn't identify the request it failed, e.g.
+// the error of the context or of a middleware, in a [url.Error] as the
+// [http.Client] does, so that the request can be recovered with FailedRequestURL.
+func withRequest(req *http.Request, err error) error {
+	if err == nil || FailedRequestURL(err) != nil {
+		return err
+	}
+	op := req.Method[:1] + strings.ToLower(req.Method[1:])
+	return &url.Error{Op: op, URL: req.URL.String(), Err: err}
+}
+
+// FailedRequestURL returns the URL of the request which the error of
+// [RequestConfig.Execute] is about, or nil if it isn't known.
+func FailedRequestURL(err error) *url.URL {
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) && apiErr.Request != nil {
+		return apiErr.Request.URL
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if u, err := url.Parse(urlErr.URL); err == nil {
+			return u
+		}
+	}
+	return nil
+}
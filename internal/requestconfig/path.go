@@ -0,0 +1,23 @@
+package requestconfig
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// PathTemplate returns the path of the URL with the leading slash removed, and
+// each segment which looks like an ID replaced by "{id}", e.g.
+// "api/payment_orders/{id}/reversals". It is used to group requests to the same
+// endpoint in metrics and logs.
+func PathTemplate(u *url.URL) string {
+	segments := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	for i, segment := range segments {
+		if uuidPattern.MatchString(segment) || strings.ContainsAny(segment, "0123456789") {
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
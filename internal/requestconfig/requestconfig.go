@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -13,7 +14,6 @@ import (
 	"net/http"
 	"net/url"
 	"runtime"
-	"strconv"
 	"strings"
 	"time"
 
@@ -98,7 +98,7 @@ func NewRequestConfig(ctx context.Context, method string, u string, body interfa
 	if b != nil {
 		req.Header.Set("Content-Type", contentType)
 	}
-	req.Header.Set("Idempotency-Key", "stainless-go-"+uuid.New().String())
+	req.Header.Set("Idempotency-Key", GeneratedIdempotencyKeyPrefix+uuid.New().String())
 	req.Header.Set("Accept", "application/json")
 
 	for k, v := range getPlatformProperties() {
@@ -125,6 +125,8 @@ func NewRequestConfig(ctx context.Context, method string, u string, body interfa
 // composing func(\*RequestConfig) error instead if possible.
 type RequestConfig struct {
 	MaxRetries     int
+	RetryPolicy    RetryPolicy
+	RetryBudget    time.Duration
 	RequestTimeout time.Duration
 	Context        context.Context
 	Request        *http.Request
@@ -141,7 +143,12 @@ type RequestConfig struct {
 	ResponseInto   **http.Response
 	OrganizationID string
 	WebhookKey     string
-	Buffer         []byte
+	// The active webhook keys, in order of preference, while a key is rotated. If
+	// set, its first key is WebhookKey.
+	WebhookKeys []string
+	Buffer      []byte
+	// The number of pages which auto-pagers fetch ahead of the current page.
+	Prefetch int
 }
 
 // middleware is exactly the same type as the Middleware type found in the [option] package,
@@ -188,10 +195,8 @@ func shouldRetry(req *http.Request, res *http.Response) bool {
 func retryDelay(res *http.Response, retryCount int) time.Duration {
 	maxDelay := 5 * time.Second
 	delay := time.Duration(500 * float64(time.Millisecond) * math.Pow(2, float64(retryCount)))
-	if res != nil {
-		if parsed, err := strconv.ParseInt(res.Header.Get("Retry-After"), 10, 64); err == nil {
-			delay = time.Duration(parsed) * time.Second
-		}
+	if retryAfter, ok := RetryAfter(res); ok {
+		delay = retryAfter
 	}
 	if delay > maxDelay {
 		delay = maxDelay
@@ -201,11 +206,51 @@ func retryDelay(res *http.Response, retryCount int) time.Duration {
 	return delay
 }
 
+// sleep waits for the given duration, returning early with the context's error
+// if it is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newAttempt summarizes the outcome of a single attempt for [apierror.RetryExhaustedError].
+func newAttempt(res *http.Response, err error, duration time.Duration) apierror.Attempt {
+	attempt := apierror.Attempt{Err: err, Duration: duration}
+	if res != nil {
+		attempt.StatusCode = res.StatusCode
+		attempt.RequestID = res.Header.Get("X-Request-Id")
+		if attempt.RequestID == "" {
+			attempt.RequestID = res.Header.Get("Request-Id")
+		}
+	}
+	return attempt
+}
+
+// attemptContext returns the context for a single attempt, which is bounded by
+// the request timeout if there is one.
+func (cfg *RequestConfig) attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if cfg.RequestTimeout == time.Duration(0) {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, cfg.RequestTimeout)
+}
+
 func (cfg *RequestConfig) Execute() (err error) {
 	cfg.Request.URL, err = cfg.BaseURL.Parse(cfg.Request.URL.String())
 	if err != nil {
 		return err
 	}
+	defer func() { err = withRequest(cfg.Request, err) }()
 
 	if len(cfg.Buffer) != 0 && cfg.Request.Body == nil {
 		cfg.Request.ContentLength = int64(len(cfg.Buffer))
@@ -218,24 +263,74 @@ func (cfg *RequestConfig) Execute() (err error) {
 		handler = applyMiddleware(cfg.Middlewares[i], handler)
 	}
 
-	var res *http.Response
-	for retryCount := 0; retryCount <= cfg.MaxRetries; retryCount += 1 {
-		ctx := cfg.Request.Context()
-		if cfg.RequestTimeout != time.Duration(0) {
-			var cancel context.CancelFunc
-			ctx, cancel = context.WithTimeout(ctx, cfg.RequestTimeout)
-			defer cancel()
-		}
+	policy := cfg.RetryPolicy
+	if policy == nil {
+		policy = DefaultRetryPolicy
+	}
 
-		res, err = handler(cfg.Request.Clone(ctx))
+	var res *http.Response
+	var delay time.Duration
+	var attempts []apierror.Attempt
+	var exhausted bool
+	// The context of the most recent attempt is only cancelled once the response
+	// body has been read, or once the attempt is discarded for a retry.
+	var cancel context.CancelFunc
+	defer func() { cancel() }()
+
+	call := &Call{ctx: cfg.Request.Context()}
+	defer func() { call.finish(err) }()
+
+	ctx := cfg.Request.Context()
+	start := time.Now()
+	// The budget also bounds an attempt which is in flight when it runs out.
+	if cfg.RetryBudget > 0 {
+		var cancelBudget context.CancelFunc
+		ctx, cancelBudget = context.WithDeadline(ctx, start.Add(cfg.RetryBudget))
+		defer cancelBudget()
+	}
+	for retryCount := 0; ; retryCount += 1 {
+		var attemptCtx context.Context
+		attemptCtx, cancel = cfg.attemptContext(ctx)
+
+		attemptStart := time.Now()
+		res, err = handler(cfg.Request.Clone(withCall(withRetryCount(attemptCtx, retryCount), call)))
 		if ctx != nil && ctx.Err() != nil {
 			return ctx.Err()
 		}
-		if !shouldRetry(cfg.Request, res) || retryCount >= cfg.MaxRetries {
+		attempts = append(attempts, newAttempt(res, err, time.Since(attemptStart)))
+
+		// If there is no way to recover the Body, then no policy can retry.
+		if cfg.Request.Body != nil && cfg.Request.GetBody == nil {
+			break
+		}
+		// Retrying a request rejected by a circuit breaker would only be rejected
+		// again, so fail fast instead.
+		if errors.Is(err, apierror.ErrCircuitOpen) {
+			break
+		}
+		var retry bool
+		delay, retry = policy.ShouldRetry(RetryAttempt{
+			Request:       cfg.Request,
+			Response:      res,
+			Err:           err,
+			RetryCount:    retryCount,
+			PreviousDelay: delay,
+		})
+		if !retry {
+			break
+		}
+		if retryCount >= cfg.MaxRetries || (cfg.RetryBudget > 0 && time.Since(start)+delay > cfg.RetryBudget) {
+			exhausted = true
 			break
 		}
 
-		// Prepare next request and wait for the retry delay
+		// Discard this attempt, then prepare the next request and wait for the
+		// retry delay
+		if res != nil {
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+		cancel()
 		if cfg.Request.GetBody != nil {
 			cfg.Request.Body, err = cfg.Request.GetBody()
 			if err != nil {
@@ -243,27 +338,28 @@ func (cfg *RequestConfig) Execute() (err error) {
 			}
 		}
 
-		time.Sleep(retryDelay(res, retryCount))
-	}
-
-	if err != nil {
-		return err
+		if err = sleep(ctx, delay); err != nil {
+			return err
+		}
 	}
 
-	if res.StatusCode >= 400 {
-		aerr := apierror.Error{Request: cfg.Request, Response: res, StatusCode: res.StatusCode}
-		contents, err := io.ReadAll(res.Body)
-		if err != nil {
-			return err
+	if err == nil && res.StatusCode >= 400 {
+		contents, readErr := io.ReadAll(res.Body)
+		if readErr != nil {
+			return readErr
 		}
 		// If there is an APIError, re-populate the response body so that debugging
 		// utilities can conveniently dump the response without issue.
 		res.Body = io.NopCloser(bytes.NewBuffer(contents))
-		err = aerr.UnmarshalJSON(contents)
-		if err != nil {
-			return err
+		err = apierror.New(cfg.Request, res, contents)
+	}
+
+	if err != nil {
+		if exhausted && len(attempts) > 1 {
+			attempts[len(attempts)-1].Err = err
+			return &apierror.RetryExhaustedError{Attempts: attempts}
 		}
-		return &aerr
+		return err
 	}
 
 	if cfg.ResponseInto != nil {
@@ -324,12 +420,26 @@ func (cfg *RequestConfig) Clone(ctx context.Context) *RequestConfig {
 		return nil
 	}
 	new := &RequestConfig{
-		MaxRetries: cfg.MaxRetries,
-		Context:    ctx,
-		Request:    req,
-		HTTPClient: cfg.HTTPClient,
+		MaxRetries:     cfg.MaxRetries,
+		RetryPolicy:    cfg.RetryPolicy,
+		RetryBudget:    cfg.RetryBudget,
+		RequestTimeout: cfg.RequestTimeout,
+		Context:        ctx,
+		Request:        req,
+		BaseURL:        cfg.BaseURL,
+		HTTPClient:     cfg.HTTPClient,
+		Middlewares:    append([]middleware(nil), cfg.Middlewares...),
+		APIKey:         cfg.APIKey,
+		OrganizationID: cfg.OrganizationID,
+		WebhookKey:     cfg.WebhookKey,
+		WebhookKeys:    cfg.WebhookKeys,
+		Buffer:         cfg.Buffer,
+		Prefetch:       cfg.Prefetch,
+	}
+	// The key of the original request, which may be the caller's, is kept.
+	if new.Request.Header.Get("Idempotency-Key") == "" {
+		new.Request.Header.Set("Idempotency-Key", GeneratedIdempotencyKeyPrefix+uuid.New().String())
 	}
-	new.Request.Header.Set("Idempotency-Key", "stainless-go-"+uuid.New().String())
 	return new
 }
 
@@ -0,0 +1,82 @@
+package requestconfig
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// GeneratedIdempotencyKeyPrefix prefixes the idempotency keys which the SDK
+// generates for requests which weren't given one. Such keys are unknown to the
+// caller, so they don't make a request safe to re-run.
+const GeneratedIdempotencyKeyPrefix = "stainless-go-"
+
+// RetryPolicy decides whether a request attempt should be retried, and how long
+// to wait before making the next attempt.
+type RetryPolicy interface {
+	// ShouldRetry is called after each attempt. It returns the delay before the next
+	// attempt, and whether there should be one at all. An attempt that should be
+	// retried when no retries remain fails with [apierror.RetryExhaustedError].
+	ShouldRetry(attempt RetryAttempt) (delay time.Duration, retry bool)
+}
+
+// RetryAttempt describes the outcome of a single request attempt.
+type RetryAttempt struct {
+	// The request that was sent. Its body must not be consumed.
+	Request *http.Request
+	// The response that was received, or nil if the attempt failed before a
+	// response was received.
+	Response *http.Response
+	// The error returned by the HTTP client or middleware, if any.
+	Err error
+	// The number of retries that were made before this attempt.
+	RetryCount int
+	// The delay that preceded this attempt, or zero for the first attempt.
+	PreviousDelay time.Duration
+}
+
+// RetryPolicyFunc is an adapter to allow the use of ordinary functions as a
+// [RetryPolicy].
+type RetryPolicyFunc func(attempt RetryAttempt) (delay time.Duration, retry bool)
+
+func (f RetryPolicyFunc) ShouldRetry(attempt RetryAttempt) (time.Duration, bool) {
+	return f(attempt)
+}
+
+// DefaultRetryPolicy retries connection errors, 408 Request Timeout, 409
+// Conflict, 429 Rate Limit and >=500 Internal errors with a short exponential
+// backoff, honoring the `x-should-retry` and `Retry-After` response headers.
+var DefaultRetryPolicy RetryPolicy = RetryPolicyFunc(func(attempt RetryAttempt) (time.Duration, bool) {
+	if !shouldRetry(attempt.Request, attempt.Response) {
+		return 0, false
+	}
+	return retryDelay(attempt.Response, attempt.RetryCount), true
+})
+
+// RetryAfter returns the delay requested by the `Retry-After` header of the
+// response, if it has one.
+func RetryAfter(res *http.Response) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+	parsed, err := strconv.ParseInt(res.Header.Get("Retry-After"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(parsed) * time.Second, true
+}
+
+type retryCountKey struct{}
+
+func withRetryCount(ctx context.Context, retryCount int) context.Context {
+	return context.WithValue(ctx, retryCountKey{}, retryCount)
+}
+
+// RetryCount returns the number of retries which preceded the attempt the context
+// belongs to. It is zero for the first attempt, and for contexts which don't
+// belong to an attempt.
+func RetryCount(ctx context.Context) int {
+	retryCount, _ := ctx.Value(retryCountKey{}).(int)
+	return retryCount
+}
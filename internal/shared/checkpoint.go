@@ -0,0 +1,184 @@
+package shared
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/Modern-Treasury/modern-treasury-go/internal/requestconfig"
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+)
+
+// Checkpoint is a position in a paginated list, from which the list can be
+// resumed, e.g. by a process which restarts after a crash. It holds the path and
+// query of the list, and its cursor, and should otherwise be treated as opaque.
+// It can be marshalled to and unmarshalled from JSON.
+type Checkpoint struct {
+	path  string
+	query url.Values
+	// The page number of the page which the query fetches, where the first page
+	// is 1.
+	page int
+	// The number of items of the page which were already seen.
+	offset int
+	// The number of items of the list which were already seen.
+	index int
+	done  bool
+}
+
+type checkpointJSON struct {
+	Path   string `json:"path,omitempty"`
+	Query  string `json:"query,omitempty"`
+	Page   int    `json:"page,omitempty"`
+	Offset int    `json:"offset,omitempty"`
+	Index  int    `json:"index,omitempty"`
+	Done   bool   `json:"done,omitempty"`
+}
+
+func (c Checkpoint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(checkpointJSON{
+		Path:   c.path,
+		Query:  c.query.Encode(),
+		Page:   c.page,
+		Offset: c.offset,
+		Index:  c.index,
+		Done:   c.done,
+	})
+}
+
+func (c *Checkpoint) UnmarshalJSON(data []byte) error {
+	var raw checkpointJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	query, err := url.ParseQuery(raw.Query)
+	if err != nil {
+		return fmt.Errorf("invalid checkpoint query: %w", err)
+	}
+	*c = Checkpoint{
+		path:   raw.Path,
+		query:  query,
+		page:   raw.Page,
+		offset: raw.Offset,
+		index:  raw.Index,
+		done:   raw.Done,
+	}
+	return nil
+}
+
+// Done reports whether the list was exhausted at the checkpoint.
+func (c Checkpoint) Done() bool {
+	return c.done
+}
+
+// Checkpoint returns the position after the last item of the page, from which
+// the list continues with the next page.
+func (r *Page[T]) Checkpoint() Checkpoint {
+	next := r.res.Header.Get("X-After-Cursor")
+	if len(next) == 0 {
+		return Checkpoint{done: true}
+	}
+	c := r.checkpoint(0)
+	c.query.Set("after_cursor", next)
+	c.page += 1
+	return c
+}
+
+// checkpoint returns the position in the page after the given number of items.
+func (r *Page[T]) checkpoint(offset int) Checkpoint {
+	page := requestconfig.PageNumber(r.cfg.Context)
+	if page == 0 {
+		page = 1
+	}
+	// The request's URL was resolved against the base URL when it was made.
+	p := strings.TrimPrefix(r.cfg.Request.URL.Path, r.cfg.BaseURL.Path)
+	return Checkpoint{
+		path:   strings.TrimPrefix(p, "/"),
+		query:  r.cfg.Request.URL.Query(),
+		page:   page,
+		offset: offset,
+	}
+}
+
+// Checkpoint returns the position after the last item returned by
+// [PageAutoPager.Current], from which the list continues. When the first request
+// of the pager failed, it returns the checkpoint which the pager was resumed
+// from, or the start of the list, which are not done.
+func (r *PageAutoPager[T]) Checkpoint() Checkpoint {
+	if r.page == nil && r.err != nil {
+		if r.start.path == "" {
+			return startCheckpoint(r.err)
+		}
+		return r.start
+	}
+	var c Checkpoint
+	switch {
+	case r.page == nil || len(r.page.Items) == 0:
+		c = Checkpoint{done: true}
+	case r.idx >= len(r.page.Items):
+		c = r.page.Checkpoint()
+	default:
+		c = r.page.checkpoint(r.idx)
+	}
+	c.index = r.run
+	return c
+}
+
+// startCheckpoint returns the start of the list whose first request failed with
+// the error, or an empty checkpoint if the error doesn't identify the request.
+// Its path is the absolute path of the request, as the base URL isn't known.
+func startCheckpoint(err error) Checkpoint {
+	u := requestconfig.FailedRequestURL(err)
+	if u == nil {
+		return Checkpoint{}
+	}
+	return Checkpoint{path: u.Path, query: u.Query(), page: 1}
+}
+
+// ResumeAutoPager returns an auto-pager which continues a list from the
+// checkpoint. The pattern is the path of the list, with `*` in place of its path
+// parameters, and guards against resuming a checkpoint of another list.
+func ResumeAutoPager[T any](ctx context.Context, pattern string, checkpoint Checkpoint, opts ...option.RequestOption) *PageAutoPager[T] {
+	if checkpoint.done {
+		return NewPageAutoPager[T](nil, nil)
+	}
+
+	var raw *http.Response
+	var res *Page[T]
+	opts = append([]option.RequestOption{option.WithResponseInto(&raw)}, opts...)
+	u := checkpoint.path + "?" + checkpoint.query.Encode()
+	cfg, err := requestconfig.NewRequestConfig(requestconfig.WithPageNumber(ctx, checkpoint.page), http.MethodGet, u, nil, &res, opts...)
+	if err != nil {
+		return resumeFailed[T](checkpoint, err)
+	}
+	// The path of a checkpoint at the start of a list is absolute, and includes
+	// the path of the base URL.
+	p := checkpoint.path
+	if strings.HasPrefix(p, "/") {
+		p = strings.TrimPrefix(strings.TrimPrefix(p, cfg.BaseURL.Path), "/")
+	}
+	if ok, _ := path.Match(pattern, p); !ok {
+		return resumeFailed[T](checkpoint, fmt.Errorf("checkpoint of %q cannot resume a list of %q", p, pattern))
+	}
+	if err = cfg.Execute(); err != nil {
+		return resumeFailed[T](checkpoint, err)
+	}
+	res.SetPageConfig(cfg, raw)
+
+	pager := NewPageAutoPager(res, nil)
+	pager.idx = checkpoint.offset
+	pager.run = checkpoint.index
+	return pager
+}
+
+// resumeFailed returns a pager which failed to resume from the checkpoint, and
+// keeps it as its checkpoint so that the progress isn't lost.
+func resumeFailed[T any](checkpoint Checkpoint, err error) *PageAutoPager[T] {
+	pager := NewPageAutoPager[T](nil, err)
+	pager.start = checkpoint
+	return pager
+}
@@ -7,7 +7,6 @@ import (
 
 	"github.com/Modern-Treasury/modern-treasury-go/internal/apijson"
 	"github.com/Modern-Treasury/modern-treasury-go/internal/requestconfig"
-	"github.com/Modern-Treasury/modern-treasury-go/option"
 )
 
 type Page[T any] struct {
@@ -32,21 +31,7 @@ func (r *Page[T]) UnmarshalJSON(data []byte) (err error) {
 // is no next page, this function will return a 'nil' for the page value, but will
 // not return an error
 func (r *Page[T]) GetNextPage() (res *Page[T], err error) {
-	next := r.res.Header.Get("X-After-Cursor")
-	if len(next) == 0 {
-		return nil, nil
-	}
-	cfg := r.cfg.Clone(r.cfg.Context)
-	cfg.Apply(option.WithQuery("after_cursor", next))
-	var raw *http.Response
-	cfg.ResponseInto = &raw
-	cfg.ResponseBodyInto = &res
-	err = cfg.Execute()
-	if err != nil {
-		return nil, err
-	}
-	res.SetPageConfig(cfg, raw)
-	return res, nil
+	return r.getNextPage(r.cfg.Context)
 }
 
 func (r *Page[T]) SetPageConfig(cfg *requestconfig.RequestConfig, res *http.Response) {
@@ -60,6 +45,12 @@ type PageAutoPager[T any] struct {
 	idx  int
 	run  int
 	err  error
+	// The position which the pager started from, which is its checkpoint while it
+	// has no page as its first request failed.
+	start Checkpoint
+
+	// Fetches the upcoming pages when the pager prefetches.
+	prefetcher *pagePrefetcher[T]
 }
 
 func NewPageAutoPager[T any](page *Page[T], err error) *PageAutoPager[T] {
@@ -70,13 +61,25 @@ func NewPageAutoPager[T any](page *Page[T], err error) *PageAutoPager[T] {
 }
 
 func (r *PageAutoPager[T]) Next() bool {
-	if r.page == nil || len(r.page.Items) == 0 {
+	if r.err != nil || r.page == nil || len(r.page.Items) == 0 {
 		return false
 	}
+	if r.prefetcher == nil && r.page.cfg != nil && r.page.cfg.Prefetch > 0 {
+		// Prefetching starts with the first item, so that the next page is ready
+		// by the time the first one is used up.
+		r.prefetch(r.page.cfg.Prefetch)
+	}
 	if r.idx >= len(r.page.Items) {
+		// The current page is kept when the next one fails, so that the pager can
+		// still be checkpointed.
+		page, err := r.nextPage()
+		if err != nil {
+			r.err = err
+			return false
+		}
 		r.idx = 0
-		r.page, r.err = r.page.GetNextPage()
-		if r.err != nil || r.page == nil {
+		r.page = page
+		if r.page == nil || len(r.page.Items) == 0 {
 			return false
 		}
 	}
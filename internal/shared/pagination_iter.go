@@ -0,0 +1,25 @@
+//go:build go1.23
+
+package shared
+
+import (
+	"iter"
+)
+
+// All returns an iterator over the remaining items of the pager, which fetches
+// pages as it goes. If fetching a page fails, the iterator yields the error with
+// the zero value of T and stops. Breaking out of the loop stops fetching pages.
+func (r *PageAutoPager[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer r.Close()
+		for r.Next() {
+			if !yield(r.Current(), nil) {
+				return
+			}
+		}
+		if err := r.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package shared
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/Modern-Treasury/modern-treasury-go/internal/requestconfig"
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+)
+
+// getNextPage fetches the next page with the given context, which the
+// configuration of the next page keeps.
+func (r *Page[T]) getNextPage(ctx context.Context) (res *Page[T], err error) {
+	next := r.res.Header.Get("X-After-Cursor")
+	if len(next) == 0 {
+		return nil, nil
+	}
+	page := requestconfig.PageNumber(r.cfg.Context)
+	if page == 0 {
+		page = 1
+	}
+	cfg := r.cfg.Clone(requestconfig.WithPageNumber(ctx, page+1))
+	cfg.Apply(option.WithQuery("after_cursor", next))
+	var raw *http.Response
+	cfg.ResponseInto = &raw
+	cfg.ResponseBodyInto = &res
+	err = cfg.Execute()
+	if err != nil {
+		return nil, err
+	}
+	res.SetPageConfig(cfg, raw)
+	return res, nil
+}
+
+type pageResult[T any] struct {
+	page *Page[T]
+	err  error
+}
+
+// pagePrefetcher fetches the pages after a page in the background, up to depth
+// pages ahead of the pages which were taken. Its goroutine only runs while
+// fewer than depth pages are fetched and not taken, and the channel of pages has
+// room for all of them, so it never blocks: a pager which is abandoned without
+// being closed stops fetching once depth pages are ahead.
+type pagePrefetcher[T any] struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	depth  int
+	pages  chan pageResult[T]
+
+	mu sync.Mutex
+	// The last page which was fetched, from which the next one is fetched.
+	last *Page[T]
+	// The number of pages which are fetched or being fetched, and not taken.
+	ahead   int
+	running bool
+	// Whether the last page, or an error, was fetched.
+	done bool
+}
+
+// prefetch starts fetching up to depth pages after the current one, in order.
+func (r *PageAutoPager[T]) prefetch(depth int) {
+	ctx, cancel := context.WithCancel(r.page.cfg.Context)
+	r.prefetcher = &pagePrefetcher[T]{
+		ctx:    ctx,
+		cancel: cancel,
+		depth:  depth,
+		pages:  make(chan pageResult[T], depth),
+		last:   r.page,
+	}
+	r.prefetcher.mu.Lock()
+	r.prefetcher.resume()
+	r.prefetcher.mu.Unlock()
+}
+
+// nextPage returns the page after the current one, from the prefetcher if the
+// pager was configured with [option.WithPrefetch].
+func (r *PageAutoPager[T]) nextPage() (*Page[T], error) {
+	if r.prefetcher == nil {
+		return r.page.GetNextPage()
+	}
+	result := r.prefetcher.take()
+	if result.err != nil || result.page == nil {
+		r.prefetcher.cancel()
+	}
+	return result.page, result.err
+}
+
+// Close stops the pager from fetching pages in the background. Pagers which are
+// abandoned without being closed stop on their own once they are the prefetch
+// depth ahead, but closing them stops them sooner.
+func (r *PageAutoPager[T]) Close() {
+	if r.prefetcher != nil {
+		r.prefetcher.cancel()
+	}
+}
+
+// take returns the next page, waiting for it to be fetched, and lets the
+// goroutine fetch another one in its place.
+func (p *pagePrefetcher[T]) take() pageResult[T] {
+	result := <-p.pages
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ahead -= 1
+	p.resume()
+	return result
+}
+
+// resume starts the goroutine if it isn't running and there is a page to fetch.
+// It must be called with the lock held.
+func (p *pagePrefetcher[T]) resume() {
+	if p.running || p.done || p.ahead >= p.depth {
+		return
+	}
+	p.running = true
+	go p.run()
+}
+
+func (p *pagePrefetcher[T]) run() {
+	for {
+		p.mu.Lock()
+		if p.done || p.ahead >= p.depth {
+			p.running = false
+			p.mu.Unlock()
+			return
+		}
+		p.ahead += 1
+		last := p.last
+		p.mu.Unlock()
+
+		next, err := last.getNextPage(p.ctx)
+
+		p.mu.Lock()
+		p.last = next
+		p.done = err != nil || next == nil || len(next.Items) == 0
+		p.mu.Unlock()
+		// There is room, as at most depth pages are ahead.
+		p.pages <- pageResult[T]{next, err}
+	}
+}
@@ -0,0 +1,260 @@
+//go:build go1.23
+
+package moderntreasury
+
+import (
+	"context"
+	"iter"
+
+	"github.com/Modern-Treasury/modern-treasury-go/internal/shared"
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+)
+
+// list account_collection_flows
+func (r *AccountCollectionFlowService) ListIter(ctx context.Context, query AccountCollectionFlowListParams, opts ...option.RequestOption) iter.Seq2[AccountCollectionFlow, error] {
+	return func(yield func(AccountCollectionFlow, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of account details for a single internal or external account.
+func (r *AccountDetailService) ListIter(ctx context.Context, accountsType shared.AccountsType, accountID string, query AccountDetailListParams, opts ...option.RequestOption) iter.Seq2[AccountDetail, error] {
+	return func(yield func(AccountDetail, error) bool) {
+		r.ListAutoPaging(ctx, accountsType, accountID, query, opts...).All()(yield)
+	}
+}
+
+// Get all balance reports for a given internal account.
+func (r *InternalAccountBalanceReportService) ListIter(ctx context.Context, internalAccountID string, query BalanceReportListParams, opts ...option.RequestOption) iter.Seq2[BalanceReport, error] {
+	return func(yield func(BalanceReport, error) bool) {
+		r.ListAutoPaging(ctx, internalAccountID, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of all connections.
+func (r *ConnectionService) ListIter(ctx context.Context, query ConnectionListParams, opts ...option.RequestOption) iter.Seq2[Connection, error] {
+	return func(yield func(Connection, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a paginated list of all counterparties.
+func (r *CounterpartyService) ListIter(ctx context.Context, query CounterpartyListParams, opts ...option.RequestOption) iter.Seq2[Counterparty, error] {
+	return func(yield func(Counterparty, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of documents.
+func (r *DocumentService) ListIter(ctx context.Context, query DocumentListParams, opts ...option.RequestOption) iter.Seq2[Document, error] {
+	return func(yield func(Document, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// list events
+func (r *EventService) ListIter(ctx context.Context, query EventListParams, opts ...option.RequestOption) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// list expected_payments
+func (r *ExpectedPaymentService) ListIter(ctx context.Context, query ExpectedPaymentListParams, opts ...option.RequestOption) iter.Seq2[ExpectedPayment, error] {
+	return func(yield func(ExpectedPayment, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// list external accounts
+func (r *ExternalAccountService) ListIter(ctx context.Context, query ExternalAccountListParams, opts ...option.RequestOption) iter.Seq2[ExternalAccount, error] {
+	return func(yield func(ExternalAccount, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of Incoming Payment Details.
+func (r *IncomingPaymentDetailService) ListIter(ctx context.Context, query IncomingPaymentDetailListParams, opts ...option.RequestOption) iter.Seq2[IncomingPaymentDetail, error] {
+	return func(yield func(IncomingPaymentDetail, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// list internal accounts
+func (r *InternalAccountService) ListIter(ctx context.Context, query InternalAccountListParams, opts ...option.RequestOption) iter.Seq2[InternalAccount, error] {
+	return func(yield func(InternalAccount, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// list invoices
+func (r *InvoiceService) ListIter(ctx context.Context, query InvoiceListParams, opts ...option.RequestOption) iter.Seq2[Invoice, error] {
+	return func(yield func(Invoice, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// list invoice_line_items
+func (r *InvoiceLineItemService) ListIter(ctx context.Context, invoiceID string, query InvoiceLineItemListParams, opts ...option.RequestOption) iter.Seq2[InvoiceLineItem, error] {
+	return func(yield func(InvoiceLineItem, error) bool) {
+		r.ListAutoPaging(ctx, invoiceID, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of ledgers.
+func (r *LedgerService) ListIter(ctx context.Context, query LedgerListParams, opts ...option.RequestOption) iter.Seq2[Ledger, error] {
+	return func(yield func(Ledger, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of ledger accounts.
+func (r *LedgerAccountService) ListIter(ctx context.Context, query LedgerAccountListParams, opts ...option.RequestOption) iter.Seq2[LedgerAccount, error] {
+	return func(yield func(LedgerAccount, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of ledger account balance monitors.
+func (r *LedgerAccountBalanceMonitorService) ListIter(ctx context.Context, query LedgerAccountBalanceMonitorListParams, opts ...option.RequestOption) iter.Seq2[LedgerAccountBalanceMonitor, error] {
+	return func(yield func(LedgerAccountBalanceMonitor, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of ledger account categories.
+func (r *LedgerAccountCategoryService) ListIter(ctx context.Context, query LedgerAccountCategoryListParams, opts ...option.RequestOption) iter.Seq2[LedgerAccountCategory, error] {
+	return func(yield func(LedgerAccountCategory, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of ledger account payouts.
+func (r *LedgerAccountPayoutService) ListIter(ctx context.Context, query LedgerAccountPayoutListParams, opts ...option.RequestOption) iter.Seq2[LedgerAccountPayout, error] {
+	return func(yield func(LedgerAccountPayout, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of all ledger entries.
+func (r *LedgerEntryService) ListIter(ctx context.Context, query LedgerEntryListParams, opts ...option.RequestOption) iter.Seq2[LedgerEntry, error] {
+	return func(yield func(LedgerEntry, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of ledger event handlers.
+func (r *LedgerEventHandlerService) ListIter(ctx context.Context, query LedgerEventHandlerListParams, opts ...option.RequestOption) iter.Seq2[LedgerEventHandlerListResponse, error] {
+	return func(yield func(LedgerEventHandlerListResponse, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of ledger transactions.
+func (r *LedgerTransactionService) ListIter(ctx context.Context, query LedgerTransactionListParams, opts ...option.RequestOption) iter.Seq2[LedgerTransaction, error] {
+	return func(yield func(LedgerTransaction, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of ledger transaction versions.
+func (r *LedgerTransactionVersionService) ListIter(ctx context.Context, query LedgerTransactionVersionListParams, opts ...option.RequestOption) iter.Seq2[LedgerTransactionVersion, error] {
+	return func(yield func(LedgerTransactionVersion, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of line items
+func (r *LineItemService) ListIter(ctx context.Context, itemizableType LineItemListParamsItemizableType, itemizableID string, query LineItemListParams, opts ...option.RequestOption) iter.Seq2[LineItem, error] {
+	return func(yield func(LineItem, error) bool) {
+		r.ListAutoPaging(ctx, itemizableType, itemizableID, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of all paper items.
+func (r *PaperItemService) ListIter(ctx context.Context, query PaperItemListParams, opts ...option.RequestOption) iter.Seq2[PaperItem, error] {
+	return func(yield func(PaperItem, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// list payment_flows
+func (r *PaymentFlowService) ListIter(ctx context.Context, query PaymentFlowListParams, opts ...option.RequestOption) iter.Seq2[PaymentFlow, error] {
+	return func(yield func(PaymentFlow, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of all payment orders
+func (r *PaymentOrderService) ListIter(ctx context.Context, query PaymentOrderListParams, opts ...option.RequestOption) iter.Seq2[PaymentOrder, error] {
+	return func(yield func(PaymentOrder, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of all reversals of a payment order.
+func (r *PaymentOrderReversalService) ListIter(ctx context.Context, paymentOrderID string, query PaymentOrderReversalListParams, opts ...option.RequestOption) iter.Seq2[Reversal, error] {
+	return func(yield func(Reversal, error) bool) {
+		r.ListAutoPaging(ctx, paymentOrderID, query, opts...).All()(yield)
+	}
+}
+
+// list payment_references
+func (r *PaymentReferenceService) ListIter(ctx context.Context, query PaymentReferenceListParams, opts ...option.RequestOption) iter.Seq2[PaymentReference, error] {
+	return func(yield func(PaymentReference, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of returns.
+func (r *ReturnService) ListIter(ctx context.Context, query ReturnListParams, opts ...option.RequestOption) iter.Seq2[ReturnObject, error] {
+	return func(yield func(ReturnObject, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of routing details for a single internal or external account.
+func (r *RoutingDetailService) ListIter(ctx context.Context, accountsType shared.AccountsType, accountID string, query RoutingDetailListParams, opts ...option.RequestOption) iter.Seq2[RoutingDetail, error] {
+	return func(yield func(RoutingDetail, error) bool) {
+		r.ListAutoPaging(ctx, accountsType, accountID, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of all transactions.
+func (r *TransactionService) ListIter(ctx context.Context, query TransactionListParams, opts ...option.RequestOption) iter.Seq2[Transaction, error] {
+	return func(yield func(Transaction, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// list transaction_line_items
+func (r *TransactionLineItemService) ListIter(ctx context.Context, query TransactionLineItemListParams, opts ...option.RequestOption) iter.Seq2[TransactionLineItem, error] {
+	return func(yield func(TransactionLineItem, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// Get a list of virtual accounts.
+func (r *VirtualAccountService) ListIter(ctx context.Context, query VirtualAccountListParams, opts ...option.RequestOption) iter.Seq2[VirtualAccount, error] {
+	return func(yield func(VirtualAccount, error) bool) {
+		r.ListAutoPaging(ctx, query, opts...).All()(yield)
+	}
+}
+
+// All returns an iterator over the remaining items of the scan. If listing a
+// range fails, the iterator yields the error with the zero value of T and stops.
+// Breaking out of the loop stops the scan.
+func (s *Scanner[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer s.Close()
+		for s.Next() {
+			if !yield(s.Current(), nil) {
+				return
+			}
+		}
+		if err := s.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+//go:build go1.23
+
+package moderntreasury_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+	"github.com/Modern-Treasury/modern-treasury-go/testutil/mtfake"
+)
+
+func TestListIter(t *testing.T) {
+	srv := mtfake.NewServer()
+	defer srv.Close()
+	for _, name := range []string{"Alice", "Bob", "Carol", "Dave", "Erin"} {
+		srv.Add("counterparties", mtfake.Object{"name": name})
+	}
+
+	requests := 0
+	client := moderntreasury.NewClient(append(srv.Options(), option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		requests++
+		return next(req)
+	}))...)
+	params := moderntreasury.CounterpartyListParams{PerPage: moderntreasury.F(int64(2))}
+
+	var names []string
+	for counterparty, err := range client.Counterparties.ListIter(context.Background(), params) {
+		if err != nil {
+			t.Fatalf("err should be nil: %s", err.Error())
+		}
+		names = append(names, counterparty.Name)
+	}
+	if len(names) != 5 || names[4] != "Erin" || requests != 3 {
+		t.Fatalf("expected all counterparties in 3 requests, got %v in %d", names, requests)
+	}
+
+	requests = 0
+	for counterparty := range client.Counterparties.ListIter(context.Background(), params) {
+		if counterparty.Name == "Bob" {
+			break
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("expected breaking out of the loop to stop fetching pages, made %d requests", requests)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var errs []error
+	for _, err := range client.Counterparties.ListIter(ctx, params) {
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || errs[0] == nil {
+		t.Fatalf("expected a single error for a cancelled context, got %v", errs)
+	}
+}
@@ -0,0 +1,91 @@
+//go:build go1.21
+
+package moderntreasury_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+)
+
+// echoTransport responds to every request with a 422 whose body echoes the
+// request body.
+type echoTransport struct{}
+
+func (t *echoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	return &http.Response{
+		StatusCode: http.StatusUnprocessableEntity,
+		Header: http.Header{
+			"Content-Type": []string{"application/json"},
+			"X-Request-Id": []string{"req_123"},
+		},
+		Body:    io.NopCloser(bytes.NewReader(body)),
+		Request: req,
+	}, nil
+}
+
+func TestLogger(t *testing.T) {
+	var out bytes.Buffer
+	client := moderntreasury.NewClient(
+		option.WithBaseURL("http://127.0.0.1:4010"),
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithHTTPClient(&http.Client{Transport: &echoTransport{}}),
+		option.WithLogger(slog.New(slog.NewJSONHandler(&out, nil)), option.LogBodies(nil)),
+		option.WithIdempotencyKey("my-key"),
+	)
+	_, err := client.ExternalAccounts.New(context.Background(), moderntreasury.ExternalAccountNewParams{
+		CounterpartyID: moderntreasury.F("9eba513a-53fd-4d6d-ad52-ccce122ab92a"),
+		AccountDetails: moderntreasury.F([]moderntreasury.ExternalAccountNewParamsAccountDetail{{
+			AccountNumber: moderntreasury.F("123456789"),
+		}}),
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var record struct {
+		Level          string `json:"level"`
+		Method         string `json:"method"`
+		Path           string `json:"path"`
+		Status         int    `json:"status"`
+		Attempt        int    `json:"attempt"`
+		IdempotencyKey string `json:"idempotency_key"`
+		RequestID      string `json:"request_id"`
+		Request        struct {
+			Headers http.Header `json:"headers"`
+			Body    string      `json:"body"`
+		} `json:"request"`
+	}
+	if err := json.NewDecoder(&out).Decode(&record); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if record.Level != "WARN" || record.Method != "POST" || record.Path != "api/external_accounts" ||
+		record.Status != 422 || record.Attempt != 1 || record.IdempotencyKey != "my-key" || record.RequestID != "req_123" {
+		t.Fatalf("unexpected log record: %+v", record)
+	}
+	if record.Request.Headers.Get("Authorization") != "[REDACTED]" {
+		t.Fatalf("expected the Authorization header to be redacted, got %q", record.Request.Headers.Get("Authorization"))
+	}
+	if strings.Contains(record.Request.Body, "123456789") || !strings.Contains(record.Request.Body, "****6789") {
+		t.Fatalf("expected the account number to be redacted, got %s", record.Request.Body)
+	}
+
+	var apierr *moderntreasury.Error
+	if !errors.As(err, &apierr) {
+		t.Fatalf("expected a *moderntreasury.Error, got %v", err)
+	}
+	if strings.Contains(string(apierr.DumpRequest(true)), "Basic") {
+		t.Fatal("expected DumpRequest to redact the Authorization header")
+	}
+}
@@ -0,0 +1,34 @@
+package option
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/Modern-Treasury/modern-treasury-go/internal/requestconfig"
+)
+
+// Call holds state shared by every attempt of a single call of an SDK method,
+// such as PaymentOrderService.New. A [Middleware] can store values in it, e.g.
+// the span of the call, and register functions to run once the call is done.
+type Call = requestconfig.Call
+
+// CallFromContext returns the call which the request attempt the context belongs
+// to is part of, or nil if it isn't part of one. It is useful in a [Middleware].
+func CallFromContext(ctx context.Context) *Call {
+	return requestconfig.CallFromContext(ctx)
+}
+
+// PageNumber returns the page of a list which the request the context belongs to
+// fetches, where the first page is 1, or zero if it isn't known. It is useful in a
+// [Middleware].
+func PageNumber(ctx context.Context) int {
+	return requestconfig.PageNumber(ctx)
+}
+
+// PathTemplate returns the path of the URL with the leading slash removed, and
+// each segment which looks like an ID replaced by "{id}", e.g.
+// "api/payment_orders/{id}/reversals", to group requests to the same endpoint in
+// metrics and logs.
+func PathTemplate(u *url.URL) string {
+	return requestconfig.PathTemplate(u)
+}
@@ -0,0 +1,240 @@
+package option
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Modern-Treasury/modern-treasury-go/internal/apierror"
+	"github.com/Modern-Treasury/modern-treasury-go/internal/requestconfig"
+)
+
+// CircuitState is the state of a single circuit of a [CircuitBreaker].
+type CircuitState int
+
+const (
+	// CircuitClosed lets all requests through.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects all requests with [moderntreasury.CircuitOpenError].
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of trial requests through, which close
+	// the circuit if they succeed and open it again if they fail.
+	CircuitHalfOpen
+)
+
+func (r CircuitState) String() string {
+	switch r {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig configures a [CircuitBreaker]. The zero value is usable.
+type CircuitBreakerConfig struct {
+	// The number of consecutive failures which open a circuit. Defaults to 5.
+	FailureThreshold int
+	// How long a circuit stays open before letting trial requests through. Defaults
+	// to 30 seconds.
+	OpenTimeout time.Duration
+	// The number of concurrent trial requests let through by a half-open circuit.
+	// Defaults to 1.
+	HalfOpenRequests int
+	// If true, each endpoint of a host has its own circuit, keyed by the host and
+	// the path template of the request, e.g. "app.moderntreasury.com/api/payment_orders".
+	// Otherwise, each host has a single circuit.
+	PerEndpoint bool
+	// IsFailure reports whether an attempt counts as a failure. Defaults to
+	// connection errors and >=500 Internal errors. Attempts which failed as the
+	// context of their call was cancelled or exceeded its deadline say nothing
+	// about the host, and are never counted.
+	IsFailure func(res *http.Response, err error) bool
+	// OnStateChange, if set, is called after a circuit changes state. It must not
+	// block.
+	OnStateChange func(key string, from CircuitState, to CircuitState)
+}
+
+// CircuitBreaker fails requests fast with [moderntreasury.CircuitOpenError] during
+// sustained outages, instead of letting every request run through all of its
+// retries. It is installed with [WithCircuitBreaker], and is safe for concurrent
+// use.
+type CircuitBreaker struct {
+	config   CircuitBreakerConfig
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	trials   int
+}
+
+type circuitTransition struct {
+	key      string
+	from, to CircuitState
+}
+
+// NewCircuitBreaker returns a CircuitBreaker with every circuit closed.
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+	if config.HalfOpenRequests <= 0 {
+		config.HalfOpenRequests = 1
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = func(res *http.Response, err error) bool {
+			return err != nil || res == nil || res.StatusCode >= http.StatusInternalServerError
+		}
+	}
+	return &CircuitBreaker{config: config, circuits: map[string]*circuit{}}
+}
+
+// WithCircuitBreaker returns a RequestOption that guards each request attempt
+// with the given circuit breaker.
+func WithCircuitBreaker(breaker *CircuitBreaker) RequestOption {
+	return WithMiddleware(breaker.Middleware)
+}
+
+// Middleware is the [Middleware] which guards each request attempt.
+func (b *CircuitBreaker) Middleware(req *http.Request, next MiddlewareNext) (*http.Response, error) {
+	key := b.key(req)
+	if err := b.allow(key); err != nil {
+		return nil, err
+	}
+	res, err := next(req)
+	if callerDone(req, err) {
+		b.release(key)
+		return res, err
+	}
+	b.record(key, !b.config.IsFailure(res, err))
+	return res, err
+}
+
+// callerDone reports whether the attempt failed while the context of its call is
+// done, rather than as the attempt timed out. Transports don't always report the
+// context's error, so any error counts.
+func callerDone(req *http.Request, err error) bool {
+	if err == nil {
+		return false
+	}
+	ctx := req.Context()
+	if call := requestconfig.CallFromContext(ctx); call != nil {
+		ctx = call.Context()
+	}
+	return ctx.Err() != nil
+}
+
+// State returns the state of the circuit with the given key.
+func (b *CircuitBreaker) State(key string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok := b.circuits[key]; ok {
+		return c.state
+	}
+	return CircuitClosed
+}
+
+func (b *CircuitBreaker) key(req *http.Request) string {
+	if b.config.PerEndpoint {
+		return req.URL.Host + "/" + requestconfig.PathTemplate(req.URL)
+	}
+	return req.URL.Host
+}
+
+func (b *CircuitBreaker) allow(key string) error {
+	var transitions []circuitTransition
+	defer func() { b.notify(transitions) }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(key)
+	if c.state == CircuitOpen {
+		retryAt := c.openedAt.Add(b.config.OpenTimeout)
+		if time.Now().Before(retryAt) {
+			return &apierror.CircuitOpenError{Key: key, RetryAt: retryAt}
+		}
+		transitions = append(transitions, c.transition(key, CircuitHalfOpen))
+	}
+	if c.state == CircuitHalfOpen {
+		if c.trials >= b.config.HalfOpenRequests {
+			return &apierror.CircuitOpenError{Key: key, RetryAt: time.Now()}
+		}
+		c.trials += 1
+	}
+	return nil
+}
+
+func (b *CircuitBreaker) record(key string, success bool) {
+	var transitions []circuitTransition
+	defer func() { b.notify(transitions) }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.circuit(key)
+	switch {
+	case success:
+		c.failures = 0
+		if c.state != CircuitClosed {
+			transitions = append(transitions, c.transition(key, CircuitClosed))
+		}
+	case c.state == CircuitHalfOpen:
+		transitions = append(transitions, c.transition(key, CircuitOpen))
+	default:
+		c.failures += 1
+		if c.state == CircuitClosed && c.failures >= b.config.FailureThreshold {
+			transitions = append(transitions, c.transition(key, CircuitOpen))
+		}
+	}
+}
+
+// release gives back the trial of an attempt which isn't recorded, so that a
+// half-open circuit lets another trial through.
+func (b *CircuitBreaker) release(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c := b.circuit(key); c.state == CircuitHalfOpen && c.trials > 0 {
+		c.trials -= 1
+	}
+}
+
+func (b *CircuitBreaker) circuit(key string) *circuit {
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+	return c
+}
+
+func (b *CircuitBreaker) notify(transitions []circuitTransition) {
+	if b.config.OnStateChange == nil {
+		return
+	}
+	for _, t := range transitions {
+		b.config.OnStateChange(t.key, t.from, t.to)
+	}
+}
+
+func (c *circuit) transition(key string, to CircuitState) circuitTransition {
+	t := circuitTransition{key: key, from: c.state, to: to}
+	c.state = to
+	c.trials = 0
+	if to == CircuitOpen {
+		c.openedAt = time.Now()
+		c.failures = 0
+	}
+	return t
+}
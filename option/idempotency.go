@@ -0,0 +1,182 @@
+package option
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/Modern-Treasury/modern-treasury-go/internal/requestconfig"
+	"github.com/google/uuid"
+)
+
+// WithIdempotencyKey returns a RequestOption that sets the `Idempotency-Key`
+// header, which is otherwise a random key generated for each request. The key is
+// sent with every retry of the request, so the API performs it at most once.
+func WithIdempotencyKey(key string) RequestOption {
+	return WithHeader("Idempotency-Key", key)
+}
+
+// WithIdempotencyKeyFrom returns a RequestOption that sets the `Idempotency-Key`
+// header to the key the store holds for the given business ID, such as an invoice
+// number. Re-running the request for the same business ID, even from another
+// process, then never performs it twice.
+func WithIdempotencyKeyFrom(store IdempotencyKeyStore, businessID string) RequestOption {
+	return func(r *requestconfig.RequestConfig) error {
+		key, err := store.IdempotencyKey(r.Context, businessID)
+		if err != nil {
+			return err
+		}
+		return r.Apply(WithIdempotencyKey(key))
+	}
+}
+
+// IdempotencyKeyStore holds the idempotency keys issued for business IDs.
+type IdempotencyKeyStore interface {
+	// IdempotencyKey returns the key for the given business ID, issuing one if there
+	// is none. It returns the same key for the same business ID until it is Reset.
+	IdempotencyKey(ctx context.Context, businessID string) (string, error)
+	// Reset discards the key for the given business ID, so that the next call to
+	// IdempotencyKey issues a new one. This is needed to deliberately perform a
+	// request again, e.g. to re-create a payment order that was cancelled.
+	Reset(ctx context.Context, businessID string) error
+}
+
+// DeriveIdempotencyKey deterministically derives an idempotency key from a
+// namespace, a business ID and a generation which is incremented each time the key
+// for the business ID is reset.
+func DeriveIdempotencyKey(namespace string, businessID string, generation int) string {
+	name := namespace + "\x00" + businessID + "\x00" + strconv.Itoa(generation)
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(name)).String()
+}
+
+// MemoryIdempotencyKeyStore is an [IdempotencyKeyStore] which holds keys in memory.
+// Keys are derived with [DeriveIdempotencyKey], so a key which was never reset is
+// the same after a process restart. Resets are only held in memory, so after a
+// restart the store returns the key from before the reset again; use a
+// [FileIdempotencyKeyStore] when keys are reset.
+type MemoryIdempotencyKeyStore struct {
+	namespace   string
+	mu          sync.Mutex
+	generations map[string]int
+}
+
+// NewMemoryIdempotencyKeyStore returns an empty MemoryIdempotencyKeyStore. Keys of
+// stores with different namespaces never collide.
+func NewMemoryIdempotencyKeyStore(namespace string) *MemoryIdempotencyKeyStore {
+	return &MemoryIdempotencyKeyStore{namespace: namespace, generations: map[string]int{}}
+}
+
+func (s *MemoryIdempotencyKeyStore) IdempotencyKey(ctx context.Context, businessID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return DeriveIdempotencyKey(s.namespace, businessID, s.generations[businessID]), nil
+}
+
+func (s *MemoryIdempotencyKeyStore) Reset(ctx context.Context, businessID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generations[businessID] += 1
+	return nil
+}
+
+// FileIdempotencyKeyStore is an [IdempotencyKeyStore] which persists keys to a
+// JSON file, so that keys survive process restarts even after being reset. The
+// file is read once when the store is created and rewritten whole on each change,
+// so it must only be used by one process at a time: processes sharing the file
+// would overwrite each other's keys. Share a store backed by a database between
+// processes instead.
+type FileIdempotencyKeyStore struct {
+	path      string
+	namespace string
+	mu        sync.Mutex
+	entries   map[string]idempotencyKeyEntry
+}
+
+type idempotencyKeyEntry struct {
+	Key        string `json:"key"`
+	Generation int    `json:"generation"`
+}
+
+// NewFileIdempotencyKeyStore returns a FileIdempotencyKeyStore backed by the file at
+// the given path, loading any keys it already holds.
+func NewFileIdempotencyKeyStore(path string, namespace string) (*FileIdempotencyKeyStore, error) {
+	s := &FileIdempotencyKeyStore{path: path, namespace: namespace, entries: map[string]idempotencyKeyEntry{}}
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(contents, &s.entries); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileIdempotencyKeyStore) IdempotencyKey(ctx context.Context, businessID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.entries[businessID]; ok {
+		return entry.Key, nil
+	}
+	entry := idempotencyKeyEntry{Key: DeriveIdempotencyKey(s.namespace, businessID, 0)}
+	if err := s.save(businessID, entry); err != nil {
+		return "", err
+	}
+	return entry.Key, nil
+}
+
+func (s *FileIdempotencyKeyStore) Reset(ctx context.Context, businessID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	generation := s.entries[businessID].Generation + 1
+	return s.save(businessID, idempotencyKeyEntry{
+		Key:        DeriveIdempotencyKey(s.namespace, businessID, generation),
+		Generation: generation,
+	})
+}
+
+// save records the entry and atomically rewrites the file.
+func (s *FileIdempotencyKeyStore) save(businessID string, entry idempotencyKeyEntry) error {
+	prev, existed := s.entries[businessID]
+	s.entries[businessID] = entry
+	err := writeFileAtomic(s.path, s.entries)
+	if err != nil {
+		if existed {
+			s.entries[businessID] = prev
+		} else {
+			delete(s.entries, businessID)
+		}
+	}
+	return err
+}
+
+// writeFileAtomic writes v as JSON to a temporary file, then renames it over path.
+func writeFileAtomic(path string, v interface{}) error {
+	contents, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,108 @@
+//go:build go1.21
+
+package option
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/Modern-Treasury/modern-treasury-go/internal/requestconfig"
+)
+
+// LoggerOption configures the logging installed by [WithLogger].
+type LoggerOption func(*loggerConfig)
+
+type loggerConfig struct {
+	redactor Redactor
+}
+
+// LogBodies returns a LoggerOption that also logs the headers and bodies of
+// requests and responses, after masking them with the given redactor. Passing nil
+// uses [DefaultRedactor].
+func LogBodies(redactor Redactor) LoggerOption {
+	if redactor == nil {
+		redactor = DefaultRedactor()
+	}
+	return func(c *loggerConfig) {
+		c.redactor = redactor
+	}
+}
+
+// WithLogger returns a RequestOption that logs every request attempt to the given
+// logger. Each record carries the method, path template, status, latency, attempt
+// number, idempotency key and request ID of the attempt. Attempts which fail are
+// logged at the warning level, and others at the info level.
+//
+// Bodies are not logged unless [LogBodies] is given.
+func WithLogger(logger *slog.Logger, opts ...LoggerOption) RequestOption {
+	config := loggerConfig{}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return WithMiddleware(func(req *http.Request, next MiddlewareNext) (*http.Response, error) {
+		start := time.Now()
+		res, err := next(req)
+
+		attrs := []slog.Attr{
+			slog.String("method", req.Method),
+			slog.String("path", requestconfig.PathTemplate(req.URL)),
+			slog.Duration("latency", time.Since(start)),
+			slog.Int("attempt", requestconfig.RetryCount(req.Context())+1),
+			slog.String("idempotency_key", req.Header.Get("Idempotency-Key")),
+		}
+		level := slog.LevelInfo
+		if err != nil {
+			level = slog.LevelWarn
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		if res != nil {
+			if res.StatusCode >= 400 {
+				level = slog.LevelWarn
+			}
+			requestID := res.Header.Get("X-Request-Id")
+			if requestID == "" {
+				requestID = res.Header.Get("Request-Id")
+			}
+			attrs = append(attrs, slog.Int("status", res.StatusCode), slog.String("request_id", requestID))
+		}
+
+		if config.redactor != nil {
+			attrs = append(attrs, slog.Group("request",
+				slog.Any("headers", RedactHeaders(config.redactor, req.Header)),
+				slog.String("body", string(config.redactor.RedactBody(req.Header.Get("Content-Type"), requestBody(req)))),
+			))
+			if res != nil {
+				body, readErr := io.ReadAll(res.Body)
+				res.Body.Close()
+				res.Body = io.NopCloser(bytes.NewReader(body))
+				if readErr != nil {
+					return nil, readErr
+				}
+				attrs = append(attrs, slog.Group("response",
+					slog.Any("headers", RedactHeaders(config.redactor, res.Header)),
+					slog.String("body", string(config.redactor.RedactBody(res.Header.Get("Content-Type"), body))),
+				))
+			}
+		}
+
+		logger.LogAttrs(req.Context(), level, "Modern Treasury API request", attrs...)
+		return res, err
+	})
+}
+
+// requestBody returns a copy of the body of the request, without consuming it.
+func requestBody(req *http.Request) []byte {
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil
+	}
+	defer body.Close()
+	contents, _ := io.ReadAll(body)
+	return contents
+}
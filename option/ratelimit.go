@@ -0,0 +1,160 @@
+package option
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Modern-Treasury/modern-treasury-go/internal/requestconfig"
+)
+
+// RateLimiter is a token bucket which paces the requests it is installed on with
+// [WithRateLimiter]. It adapts to the `Retry-After` and `X-RateLimit-*` headers of
+// the responses it sees, so that once the API signals a limit every request
+// sharing the limiter waits, rather than each of them spending retries on 429
+// responses.
+//
+// A RateLimiter is safe for concurrent use, and is usually given to
+// [moderntreasury.NewClient] so that it is shared by all of the client's services.
+type RateLimiter struct {
+	mu           sync.Mutex
+	rate         float64
+	burst        float64
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+}
+
+// NewRateLimiter returns a RateLimiter which allows requestsPerSecond requests per
+// second on average, with bursts of up to burst requests. When requestsPerSecond
+// is zero, requests are only paced by the rate limit headers of the API.
+func NewRateLimiter(requestsPerSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// WithRateLimiter returns a RequestOption that waits for the given limiter before
+// each request attempt, and updates it from each response.
+func WithRateLimiter(limiter *RateLimiter) RequestOption {
+	return WithMiddleware(func(req *http.Request, next MiddlewareNext) (*http.Response, error) {
+		if err := limiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+		res, err := next(req)
+		limiter.Observe(res)
+		return res, err
+	})
+}
+
+// Wait blocks until a request may be made, or until the context is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	delay := l.reserve()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		if l.rate > 0 {
+			l.tokens += 1
+		}
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token from the bucket, and returns how long to wait before it
+// may be used.
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.refill(now)
+
+	var delay time.Duration
+	if l.rate > 0 {
+		l.tokens -= 1
+		if l.tokens < 0 {
+			delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+		}
+	}
+	if blocked := l.blockedUntil.Sub(now); blocked > delay {
+		delay = blocked
+	}
+	return delay
+}
+
+func (l *RateLimiter) refill(now time.Time) {
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+}
+
+// Observe updates the limiter from the rate limit headers of the response. It is
+// called by [WithRateLimiter] after every attempt, and does nothing when the
+// response is nil.
+func (l *RateLimiter) Observe(res *http.Response) {
+	if res == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.refill(now)
+
+	if remaining, err := strconv.ParseFloat(res.Header.Get("X-RateLimit-Remaining"), 64); err == nil {
+		if remaining < l.tokens {
+			l.tokens = remaining
+		}
+		if remaining <= 0 {
+			if reset, ok := rateLimitReset(res.Header.Get("X-RateLimit-Reset"), now); ok {
+				l.blockUntil(reset)
+			}
+		}
+	}
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		if retryAfter, ok := requestconfig.RetryAfter(res); ok {
+			l.blockUntil(now.Add(retryAfter))
+		} else if l.blockedUntil.Before(now) {
+			l.blockUntil(now.Add(time.Second))
+		}
+	}
+}
+
+func (l *RateLimiter) blockUntil(t time.Time) {
+	if t.After(l.blockedUntil) {
+		l.blockedUntil = t
+	}
+}
+
+// rateLimitReset parses the `X-RateLimit-Reset` header, which is either a Unix
+// timestamp or a number of seconds from now.
+func rateLimitReset(header string, now time.Time) (time.Time, bool) {
+	reset, err := strconv.ParseInt(header, 10, 64)
+	if err != nil || reset < 0 {
+		return time.Time{}, false
+	}
+	if reset > 1_000_000_000 {
+		return time.Unix(reset, 0), true
+	}
+	return now.Add(time.Duration(reset) * time.Second), true
+}
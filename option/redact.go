@@ -0,0 +1,124 @@
+package option
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Redactor masks sensitive values in requests and responses before they are
+// logged or recorded.
+type Redactor interface {
+	// RedactHeader returns the value to record for the given header.
+	RedactHeader(name string, value string) string
+	// RedactBody returns the body to record for a body of the given content type.
+	RedactBody(contentType string, body []byte) []byte
+}
+
+// FieldRedactor is a [Redactor] which fully masks the values of the given
+// headers, and masks all but the last four characters of the given fields of JSON
+// bodies, wherever they are nested. Bodies which are not JSON are returned as
+// is.
+type FieldRedactor struct {
+	// Header names, matched case-insensitively.
+	Headers []string
+	// JSON object keys, matched exactly.
+	Fields []string
+}
+
+// DefaultRedactor returns a [FieldRedactor] which masks the `Authorization`
+// header, and the account numbers, routing numbers and tax IDs in JSON bodies.
+func DefaultRedactor() *FieldRedactor {
+	return &FieldRedactor{
+		Headers: []string{"Authorization", "X-Signature"},
+		Fields: []string{
+			"account_number",
+			"originating_account_number",
+			"routing_number",
+			"originating_routing_number",
+			"taxpayer_identifier",
+			"tax_id",
+		},
+	}
+}
+
+func (r *FieldRedactor) RedactHeader(name string, value string) string {
+	for _, header := range r.Headers {
+		if http.CanonicalHeaderKey(header) == http.CanonicalHeaderKey(name) {
+			return "[REDACTED]"
+		}
+	}
+	return value
+}
+
+func (r *FieldRedactor) RedactBody(contentType string, body []byte) []byte {
+	if len(body) == 0 || !strings.Contains(contentType, "json") {
+		return body
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return body
+	}
+	if !r.redactValue(v) {
+		return body
+	}
+	redacted, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+// redactValue masks the sensitive fields nested in v in place, and reports
+// whether there were any.
+func (r *FieldRedactor) redactValue(v interface{}) (redacted bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if s, ok := value.(string); ok && r.isField(key) {
+				v[key] = mask(s)
+				redacted = true
+			} else if r.redactValue(value) {
+				redacted = true
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			if r.redactValue(value) {
+				redacted = true
+			}
+		}
+	}
+	return redacted
+}
+
+func (r *FieldRedactor) isField(key string) bool {
+	for _, field := range r.Fields {
+		if field == key {
+			return true
+		}
+	}
+	return false
+}
+
+// mask replaces all but the last four characters of s.
+func mask(s string) string {
+	if len(s) <= 4 {
+		return "****"
+	}
+	return "****" + s[len(s)-4:]
+}
+
+// RedactHeaders returns a copy of the headers with each value redacted.
+func RedactHeaders(redactor Redactor, headers http.Header) http.Header {
+	redacted := make(http.Header, len(headers))
+	for name, values := range headers {
+		for _, value := range values {
+			redacted.Add(name, redactor.RedactHeader(name, value))
+		}
+	}
+	return redacted
+}
@@ -75,6 +75,26 @@ func WithMaxRetries(retries int) RequestOption {
 	}
 }
 
+// WithPrefetch returns a RequestOption that makes the auto-pagers of list
+// methods fetch up to depth pages ahead of the page being iterated, in the
+// background, so that iteration doesn't wait on the network at every page
+// boundary. Prefetching starts when the pager returns its first item. Items are
+// still returned in order, and at most depth pages are held ahead of the current
+// one. Prefetching stops when the request's context is done, when the pager is
+// closed with Close, or once a pager which is abandoned is depth pages ahead.
+// When given 0, pages are fetched as they are needed, which is the default.
+//
+// WithPrefetch panics when depth is negative.
+func WithPrefetch(depth int) RequestOption {
+	if depth < 0 {
+		panic("option: cannot prefetch fewer than 0 pages")
+	}
+	return func(r *requestconfig.RequestConfig) error {
+		r.Prefetch = depth
+		return nil
+	}
+}
+
 // WithHeader returns a RequestOption that sets the header value to the associated key. It overwrites
 // any value if there was one already present.
 func WithHeader(key, value string) RequestOption {
@@ -214,6 +234,19 @@ func WithOrganizationID(value string) RequestOption {
 func WithWebhookKey(value string) RequestOption {
 	return func(r *requestconfig.RequestConfig) error {
 		r.WebhookKey = value
+		r.WebhookKeys = nil
+		return nil
+	}
+}
+
+// WithWebhookKeys returns a RequestOption that sets the active webhook keys while
+// a key is rotated. Webhooks signed with any of the keys are verified, and the
+// primary key is the client setting "webhook_key". The secondary keys are listed
+// in order of preference, e.g. the key which is being retired last.
+func WithWebhookKeys(primary string, secondary ...string) RequestOption {
+	return func(r *requestconfig.RequestConfig) error {
+		r.WebhookKey = primary
+		r.WebhookKeys = append([]string{primary}, secondary...)
 		return nil
 	}
 }
@@ -0,0 +1,160 @@
+package option
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Modern-Treasury/modern-treasury-go/internal/requestconfig"
+)
+
+// RetryPolicy decides whether a request attempt should be retried, and how long
+// to wait before making the next attempt. The number of attempts is still bounded
+// by [WithMaxRetries].
+type RetryPolicy = requestconfig.RetryPolicy
+
+// RetryAttempt describes the outcome of a single request attempt, as given to a
+// [RetryPolicy].
+type RetryAttempt = requestconfig.RetryAttempt
+
+// RetryPolicyFunc is an adapter to allow the use of ordinary functions as a
+// [RetryPolicy].
+type RetryPolicyFunc = requestconfig.RetryPolicyFunc
+
+// WithRetryPolicy returns a RequestOption that sets the policy deciding which
+// failed attempts are retried and how long to wait between them. Passing nil
+// restores the default policy.
+func WithRetryPolicy(policy RetryPolicy) RequestOption {
+	return func(r *requestconfig.RequestConfig) error {
+		r.RetryPolicy = policy
+		return nil
+	}
+}
+
+// WithRetryBudget returns a RequestOption that caps the total time spent on a
+// request across all of its attempts and the delays between them. A retry is not
+// attempted when its delay would exceed the remaining budget; the request then
+// fails with [moderntreasury.RetryExhaustedError]. An attempt which is still in
+// flight when the budget runs out is cancelled, and the request fails with
+// [context.DeadlineExceeded]. Zero means no budget.
+func WithRetryBudget(budget time.Duration) RequestOption {
+	return func(r *requestconfig.RequestConfig) error {
+		r.RetryBudget = budget
+		return nil
+	}
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured. It retries
+// connection errors, 408 Request Timeout, 409 Conflict, 429 Rate Limit and >=500
+// Internal errors with a short exponential backoff.
+func DefaultRetryPolicy() RetryPolicy {
+	return requestconfig.DefaultRetryPolicy
+}
+
+// FullJitterRetryPolicy returns a RetryPolicy that retries the same attempts as
+// [DefaultRetryPolicy], waiting a random duration between zero and an
+// exponentially growing ceiling of base * 2^retries, capped at max.
+func FullJitterRetryPolicy(base, max time.Duration) RetryPolicy {
+	return RetryPolicyFunc(func(attempt RetryAttempt) (time.Duration, bool) {
+		if _, ok := requestconfig.DefaultRetryPolicy.ShouldRetry(attempt); !ok {
+			return 0, false
+		}
+		ceiling := time.Duration(float64(base) * math.Pow(2, float64(attempt.RetryCount)))
+		if ceiling > max || ceiling <= 0 {
+			ceiling = max
+		}
+		return withRetryAfter(attempt.Response, randomDuration(0, ceiling), max), true
+	})
+}
+
+// DecorrelatedJitterRetryPolicy returns a RetryPolicy that retries the same
+// attempts as [DefaultRetryPolicy], waiting a random duration between base and
+// three times the previous delay, capped at max.
+func DecorrelatedJitterRetryPolicy(base, max time.Duration) RetryPolicy {
+	return RetryPolicyFunc(func(attempt RetryAttempt) (time.Duration, bool) {
+		if _, ok := requestconfig.DefaultRetryPolicy.ShouldRetry(attempt); !ok {
+			return 0, false
+		}
+		prev := attempt.PreviousDelay
+		if prev < base {
+			prev = base
+		}
+		delay := randomDuration(base, 3*prev)
+		if delay > max {
+			delay = max
+		}
+		return withRetryAfter(attempt.Response, delay, max), true
+	})
+}
+
+// IdempotentRetryPolicy returns a RetryPolicy that never retries a POST request
+// unless the caller set its `Idempotency-Key`, e.g. with [WithIdempotencyKey],
+// and otherwise defers to the given policy. The random key which the SDK sends
+// with every request doesn't count, as it is lost when the process restarts and
+// the request is made again. Passing nil uses [DefaultRetryPolicy].
+func IdempotentRetryPolicy(policy RetryPolicy) RetryPolicy {
+	if policy == nil {
+		policy = requestconfig.DefaultRetryPolicy
+	}
+	return RetryPolicyFunc(func(attempt RetryAttempt) (time.Duration, bool) {
+		key := attempt.Request.Header.Get("Idempotency-Key")
+		if attempt.Request.Method == http.MethodPost && (key == "" || strings.HasPrefix(key, requestconfig.GeneratedIdempotencyKeyPrefix)) {
+			return 0, false
+		}
+		return policy.ShouldRetry(attempt)
+	})
+}
+
+// SkipStatusRetryPolicy returns a RetryPolicy that never retries responses with
+// one of the given status codes, and otherwise defers to the given policy.
+// Passing nil uses [DefaultRetryPolicy]. For example, to avoid retrying 409
+// Conflict when creating a payment order:
+//
+//	client.PaymentOrders.New(ctx, params, option.WithRetryPolicy(
+//		option.SkipStatusRetryPolicy(nil, http.StatusConflict),
+//	))
+func SkipStatusRetryPolicy(policy RetryPolicy, statusCodes ...int) RetryPolicy {
+	if policy == nil {
+		policy = requestconfig.DefaultRetryPolicy
+	}
+	return RetryPolicyFunc(func(attempt RetryAttempt) (time.Duration, bool) {
+		if attempt.Response != nil {
+			for _, code := range statusCodes {
+				if attempt.Response.StatusCode == code {
+					return 0, false
+				}
+			}
+		}
+		return policy.ShouldRetry(attempt)
+	})
+}
+
+// randomDuration returns a random duration in the half-open interval [min, max).
+func randomDuration(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	return min + time.Duration(rand.Int63n(int64(max-min)))
+}
+
+// withRetryAfter raises delay to the `Retry-After` header of the response, if
+// there is one, without exceeding max.
+func withRetryAfter(res *http.Response, delay, max time.Duration) time.Duration {
+	if retryAfter, ok := requestconfig.RetryAfter(res); ok && retryAfter > delay {
+		delay = retryAfter
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// RetryCount returns the number of retries which preceded the request attempt the
+// context belongs to, which is useful in a [Middleware]. It is zero for the first
+// attempt.
+func RetryCount(ctx context.Context) int {
+	return requestconfig.RetryCount(ctx)
+}
@@ -0,0 +1,187 @@
+package otel
+
+import (
+	"net/http"
+	"strings"
+)
+
+// operation is an endpoint of the API, and the SDK method which calls it.
+type operation struct {
+	method string
+	path   string
+	name   string
+}
+
+// operations lists the endpoints in api.md. Path segments in braces match any
+// segment.
+var operations = []operation{
+	{http.MethodGet, "api/ping", "Client.Ping"},
+	{http.MethodGet, "api/connections", "ConnectionService.List"},
+	{http.MethodPost, "api/counterparties", "CounterpartyService.New"},
+	{http.MethodGet, "api/counterparties/{id}", "CounterpartyService.Get"},
+	{http.MethodPatch, "api/counterparties/{id}", "CounterpartyService.Update"},
+	{http.MethodGet, "api/counterparties", "CounterpartyService.List"},
+	{http.MethodDelete, "api/counterparties/{id}", "CounterpartyService.Delete"},
+	{http.MethodPost, "api/counterparties/{id}/collect_account", "CounterpartyService.CollectAccount"},
+	{http.MethodGet, "api/events/{id}", "EventService.Get"},
+	{http.MethodGet, "api/events", "EventService.List"},
+	{http.MethodPost, "api/expected_payments", "ExpectedPaymentService.New"},
+	{http.MethodGet, "api/expected_payments/{id}", "ExpectedPaymentService.Get"},
+	{http.MethodPatch, "api/expected_payments/{id}", "ExpectedPaymentService.Update"},
+	{http.MethodGet, "api/expected_payments", "ExpectedPaymentService.List"},
+	{http.MethodDelete, "api/expected_payments/{id}", "ExpectedPaymentService.Delete"},
+	{http.MethodPost, "api/external_accounts", "ExternalAccountService.New"},
+	{http.MethodGet, "api/external_accounts/{id}", "ExternalAccountService.Get"},
+	{http.MethodPatch, "api/external_accounts/{id}", "ExternalAccountService.Update"},
+	{http.MethodGet, "api/external_accounts", "ExternalAccountService.List"},
+	{http.MethodDelete, "api/external_accounts/{id}", "ExternalAccountService.Delete"},
+	{http.MethodPost, "api/external_accounts/{id}/complete_verification", "ExternalAccountService.CompleteVerification"},
+	{http.MethodPost, "api/external_accounts/{id}/verify", "ExternalAccountService.Verify"},
+	{http.MethodGet, "api/incoming_payment_details/{id}", "IncomingPaymentDetailService.Get"},
+	{http.MethodPatch, "api/incoming_payment_details/{id}", "IncomingPaymentDetailService.Update"},
+	{http.MethodGet, "api/incoming_payment_details", "IncomingPaymentDetailService.List"},
+	{http.MethodPost, "api/simulations/incoming_payment_details/create_async", "IncomingPaymentDetailService.NewAsync"},
+	{http.MethodPost, "api/invoices", "InvoiceService.New"},
+	{http.MethodGet, "api/invoices/{id}", "InvoiceService.Get"},
+	{http.MethodPatch, "api/invoices/{id}", "InvoiceService.Update"},
+	{http.MethodGet, "api/invoices", "InvoiceService.List"},
+	{http.MethodPut, "api/invoices/{id}/payment_orders/{payment_order_id}", "InvoiceService.AddPaymentOrder"},
+	{http.MethodPost, "api/invoices/{invoice_id}/invoice_line_items", "InvoiceLineItemService.New"},
+	{http.MethodGet, "api/invoices/{invoice_id}/invoice_line_items/{id}", "InvoiceLineItemService.Get"},
+	{http.MethodPatch, "api/invoices/{invoice_id}/invoice_line_items/{id}", "InvoiceLineItemService.Update"},
+	{http.MethodGet, "api/invoices/{invoice_id}/invoice_line_items", "InvoiceLineItemService.List"},
+	{http.MethodDelete, "api/invoices/{invoice_id}/invoice_line_items/{id}", "InvoiceLineItemService.Delete"},
+	{http.MethodPost, "api/documents", "DocumentService.New"},
+	{http.MethodGet, "api/documents/{id}", "DocumentService.Get"},
+	{http.MethodGet, "api/documents", "DocumentService.List"},
+	{http.MethodPost, "api/account_collection_flows", "AccountCollectionFlowService.New"},
+	{http.MethodGet, "api/account_collection_flows/{id}", "AccountCollectionFlowService.Get"},
+	{http.MethodPatch, "api/account_collection_flows/{id}", "AccountCollectionFlowService.Update"},
+	{http.MethodGet, "api/account_collection_flows", "AccountCollectionFlowService.List"},
+	{http.MethodPost, "api/{accounts_type}/{account_id}/account_details", "AccountDetailService.New"},
+	{http.MethodGet, "api/{accounts_type}/{account_id}/account_details/{id}", "AccountDetailService.Get"},
+	{http.MethodGet, "api/{accounts_type}/{account_id}/account_details", "AccountDetailService.List"},
+	{http.MethodDelete, "api/{accounts_type}/{account_id}/account_details/{id}", "AccountDetailService.Delete"},
+	{http.MethodPost, "api/{accounts_type}/{account_id}/routing_details", "RoutingDetailService.New"},
+	{http.MethodGet, "api/{accounts_type}/{account_id}/routing_details/{id}", "RoutingDetailService.Get"},
+	{http.MethodGet, "api/{accounts_type}/{account_id}/routing_details", "RoutingDetailService.List"},
+	{http.MethodDelete, "api/{accounts_type}/{account_id}/routing_details/{id}", "RoutingDetailService.Delete"},
+	{http.MethodPost, "api/internal_accounts", "InternalAccountService.New"},
+	{http.MethodGet, "api/internal_accounts/{id}", "InternalAccountService.Get"},
+	{http.MethodPatch, "api/internal_accounts/{id}", "InternalAccountService.Update"},
+	{http.MethodGet, "api/internal_accounts", "InternalAccountService.List"},
+	{http.MethodGet, "api/internal_accounts/{internal_account_id}/balance_reports/{id}", "InternalAccountBalanceReportService.Get"},
+	{http.MethodGet, "api/internal_accounts/{internal_account_id}/balance_reports", "InternalAccountBalanceReportService.List"},
+	{http.MethodPost, "api/ledgers", "LedgerService.New"},
+	{http.MethodGet, "api/ledgers/{id}", "LedgerService.Get"},
+	{http.MethodPatch, "api/ledgers/{id}", "LedgerService.Update"},
+	{http.MethodGet, "api/ledgers", "LedgerService.List"},
+	{http.MethodDelete, "api/ledgers/{id}", "LedgerService.Delete"},
+	{http.MethodPost, "api/ledgerable_events", "LedgerableEventService.New"},
+	{http.MethodGet, "api/ledgerable_events/{id}", "LedgerableEventService.Get"},
+	{http.MethodPost, "api/ledger_account_categories", "LedgerAccountCategoryService.New"},
+	{http.MethodGet, "api/ledger_account_categories/{id}", "LedgerAccountCategoryService.Get"},
+	{http.MethodPatch, "api/ledger_account_categories/{id}", "LedgerAccountCategoryService.Update"},
+	{http.MethodGet, "api/ledger_account_categories", "LedgerAccountCategoryService.List"},
+	{http.MethodDelete, "api/ledger_account_categories/{id}", "LedgerAccountCategoryService.Delete"},
+	{http.MethodPut, "api/ledger_account_categories/{id}/ledger_accounts/{ledger_account_id}", "LedgerAccountCategoryService.AddLedgerAccount"},
+	{http.MethodPut, "api/ledger_account_categories/{id}/ledger_account_categories/{sub_category_id}", "LedgerAccountCategoryService.AddNestedCategory"},
+	{http.MethodDelete, "api/ledger_account_categories/{id}/ledger_accounts/{ledger_account_id}", "LedgerAccountCategoryService.RemoveLedgerAccount"},
+	{http.MethodDelete, "api/ledger_account_categories/{id}/ledger_account_categories/{sub_category_id}", "LedgerAccountCategoryService.RemoveNestedCategory"},
+	{http.MethodPost, "api/ledger_accounts", "LedgerAccountService.New"},
+	{http.MethodGet, "api/ledger_accounts/{id}", "LedgerAccountService.Get"},
+	{http.MethodPatch, "api/ledger_accounts/{id}", "LedgerAccountService.Update"},
+	{http.MethodGet, "api/ledger_accounts", "LedgerAccountService.List"},
+	{http.MethodDelete, "api/ledger_accounts/{id}", "LedgerAccountService.Delete"},
+	{http.MethodPost, "api/ledger_account_balance_monitors", "LedgerAccountBalanceMonitorService.New"},
+	{http.MethodGet, "api/ledger_account_balance_monitors/{id}", "LedgerAccountBalanceMonitorService.Get"},
+	{http.MethodPatch, "api/ledger_account_balance_monitors/{id}", "LedgerAccountBalanceMonitorService.Update"},
+	{http.MethodGet, "api/ledger_account_balance_monitors", "LedgerAccountBalanceMonitorService.List"},
+	{http.MethodDelete, "api/ledger_account_balance_monitors/{id}", "LedgerAccountBalanceMonitorService.Delete"},
+	{http.MethodPost, "api/ledger_account_payouts", "LedgerAccountPayoutService.New"},
+	{http.MethodGet, "api/ledger_account_payouts/{id}", "LedgerAccountPayoutService.Get"},
+	{http.MethodPatch, "api/ledger_account_payouts/{id}", "LedgerAccountPayoutService.Update"},
+	{http.MethodGet, "api/ledger_account_payouts", "LedgerAccountPayoutService.List"},
+	{http.MethodPost, "api/ledger_account_statements", "LedgerAccountStatementService.New"},
+	{http.MethodGet, "api/ledger_account_statements/{id}", "LedgerAccountStatementService.Get"},
+	{http.MethodGet, "api/ledger_entries/{id}", "LedgerEntryService.Get"},
+	{http.MethodGet, "api/ledger_entries", "LedgerEntryService.List"},
+	{http.MethodPost, "api/ledger_event_handlers", "LedgerEventHandlerService.New"},
+	{http.MethodGet, "api/ledger_event_handlers/{id}", "LedgerEventHandlerService.Get"},
+	{http.MethodGet, "api/ledger_event_handlers", "LedgerEventHandlerService.List"},
+	{http.MethodDelete, "api/ledger_event_handlers/{id}", "LedgerEventHandlerService.Delete"},
+	{http.MethodPost, "api/ledger_transactions", "LedgerTransactionService.New"},
+	{http.MethodGet, "api/ledger_transactions/{id}", "LedgerTransactionService.Get"},
+	{http.MethodPatch, "api/ledger_transactions/{id}", "LedgerTransactionService.Update"},
+	{http.MethodGet, "api/ledger_transactions", "LedgerTransactionService.List"},
+	{http.MethodPost, "api/ledger_transactions/{id}/reversal", "LedgerTransactionService.NewReversal"},
+	{http.MethodGet, "api/ledger_transaction_versions", "LedgerTransactionVersionService.List"},
+	{http.MethodGet, "api/{itemizable_type}/{itemizable_id}/line_items/{id}", "LineItemService.Get"},
+	{http.MethodPatch, "api/{itemizable_type}/{itemizable_id}/line_items/{id}", "LineItemService.Update"},
+	{http.MethodGet, "api/{itemizable_type}/{itemizable_id}/line_items", "LineItemService.List"},
+	{http.MethodPost, "api/payment_flows", "PaymentFlowService.New"},
+	{http.MethodGet, "api/payment_flows/{id}", "PaymentFlowService.Get"},
+	{http.MethodPatch, "api/payment_flows/{id}", "PaymentFlowService.Update"},
+	{http.MethodGet, "api/payment_flows", "PaymentFlowService.List"},
+	{http.MethodPost, "api/payment_orders", "PaymentOrderService.New"},
+	{http.MethodGet, "api/payment_orders/{id}", "PaymentOrderService.Get"},
+	{http.MethodPatch, "api/payment_orders/{id}", "PaymentOrderService.Update"},
+	{http.MethodGet, "api/payment_orders", "PaymentOrderService.List"},
+	{http.MethodPost, "api/payment_orders/create_async", "PaymentOrderService.NewAsync"},
+	{http.MethodPost, "api/payment_orders/{payment_order_id}/reversals", "PaymentOrderReversalService.New"},
+	{http.MethodGet, "api/payment_orders/{payment_order_id}/reversals/{reversal_id}", "PaymentOrderReversalService.Get"},
+	{http.MethodGet, "api/payment_orders/{payment_order_id}/reversals", "PaymentOrderReversalService.List"},
+	{http.MethodGet, "api/payment_references/{id}", "PaymentReferenceService.Get"},
+	{http.MethodGet, "api/payment_references", "PaymentReferenceService.List"},
+	{http.MethodPost, "api/returns", "ReturnService.New"},
+	{http.MethodGet, "api/returns/{id}", "ReturnService.Get"},
+	{http.MethodGet, "api/returns", "ReturnService.List"},
+	{http.MethodGet, "api/transactions/{id}", "TransactionService.Get"},
+	{http.MethodPatch, "api/transactions/{id}", "TransactionService.Update"},
+	{http.MethodGet, "api/transactions", "TransactionService.List"},
+	{http.MethodGet, "api/transaction_line_items/{id}", "TransactionLineItemService.Get"},
+	{http.MethodGet, "api/transaction_line_items", "TransactionLineItemService.List"},
+	{http.MethodGet, "api/validations/routing_numbers", "ValidationService.ValidateRoutingNumber"},
+	{http.MethodGet, "api/paper_items/{id}", "PaperItemService.Get"},
+	{http.MethodGet, "api/paper_items", "PaperItemService.List"},
+	{http.MethodPost, "api/virtual_accounts", "VirtualAccountService.New"},
+	{http.MethodGet, "api/virtual_accounts/{id}", "VirtualAccountService.Get"},
+	{http.MethodPatch, "api/virtual_accounts/{id}", "VirtualAccountService.Update"},
+	{http.MethodGet, "api/virtual_accounts", "VirtualAccountService.List"},
+	{http.MethodDelete, "api/virtual_accounts/{id}", "VirtualAccountService.Delete"},
+}
+
+// operationName returns the SDK method which calls the endpoint of the request,
+// e.g. "PaymentOrderService.List", or false if the endpoint is unknown.
+func operationName(req *http.Request) (string, bool) {
+	segments := strings.Split(strings.TrimPrefix(req.URL.Path, "/"), "/")
+	best, bestFixed := "", -1
+	for _, op := range operations {
+		if op.method != req.Method {
+			continue
+		}
+		if fixed, ok := matchPath(op.path, segments); ok && fixed > bestFixed {
+			best, bestFixed = op.name, fixed
+		}
+	}
+	return best, bestFixed >= 0
+}
+
+// matchPath reports whether the path segments match the template, and how many
+// of the template's segments are fixed.
+func matchPath(template string, segments []string) (fixed int, ok bool) {
+	parts := strings.Split(template, "/")
+	if len(parts) != len(segments) {
+		return 0, false
+	}
+	for i, part := range parts {
+		if strings.HasPrefix(part, "{") {
+			continue
+		}
+		if part != segments[i] {
+			return 0, false
+		}
+		fixed += 1
+	}
+	return fixed, true
+}
@@ -0,0 +1,217 @@
+// Package otel instruments the Modern Treasury client with OpenTelemetry tracing
+// and metrics.
+//
+// Its middleware creates an internal span for each call of an SDK method, such as
+// PaymentOrderService.List, with a client span for each attempt of the call:
+//
+//	client := moderntreasury.NewClient(
+//		option.WithMiddleware(otel.Middleware()),
+//	)
+package otel
+
+import (
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/Modern-Treasury/modern-treasury-go/otel"
+
+// version returns the version of this module, as recorded in the build info of
+// the binary, or an empty string if it isn't known.
+func version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == instrumentationName {
+			return dep.Version
+		}
+	}
+	return ""
+}
+
+// Option configures the instrumentation installed by [Middleware].
+type Option func(*config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagator     propagation.TextMapPropagator
+}
+
+// WithTracerProvider returns an Option that sets the tracer provider, which
+// defaults to the global one.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider returns an Option that sets the meter provider, which defaults
+// to the global one.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = provider
+	}
+}
+
+// WithPropagator returns an Option that sets the propagator used to inject the
+// trace context into request headers, which defaults to the global one.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = propagator
+	}
+}
+
+// callState is the state of a traced call, which is shared by its attempts.
+type callState struct {
+	span       trace.Span
+	start      time.Time
+	attrs      []attribute.KeyValue
+	retryCount int
+	statusCode int
+}
+
+type callStateKey struct{}
+
+// Middleware returns an [option.Middleware] which traces each call of an SDK
+// method, and each attempt of the call. Call spans are named after the SDK
+// method, and carry its retry count and, when auto-paging, its page number.
+//
+// It also records the duration of each call to the
+// "moderntreasury.client.call.duration" histogram, and its number of retries to
+// the "moderntreasury.client.call.retries" histogram.
+func Middleware(opts ...Option) option.Middleware {
+	c := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+		propagator:     otel.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	tracer := c.tracerProvider.Tracer(instrumentationName, trace.WithInstrumentationVersion(version()))
+	meter := c.meterProvider.Meter(instrumentationName, metric.WithInstrumentationVersion(version()))
+	duration, err := meter.Float64Histogram(
+		"moderntreasury.client.call.duration",
+		metric.WithDescription("The duration of calls to the Modern Treasury API, including all retries."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+	retries, err := meter.Int64Histogram(
+		"moderntreasury.client.call.retries",
+		metric.WithDescription("The number of retries made by calls to the Modern Treasury API."),
+		metric.WithUnit("{retry}"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		ctx := req.Context()
+		call := option.CallFromContext(ctx)
+
+		var state *callState
+		if call != nil {
+			state, _ = call.Value(callStateKey{}).(*callState)
+		}
+		if state == nil {
+			state = startCall(tracer, req)
+			finish := func(err error) {
+				state.span.SetAttributes(attribute.Int("moderntreasury.retry_count", state.retryCount))
+				attrs := state.attrs
+				if state.statusCode != 0 {
+					attrs = append(attrs, attribute.Int("http.response.status_code", state.statusCode))
+				}
+				if err != nil {
+					state.span.RecordError(err)
+					state.span.SetStatus(codes.Error, err.Error())
+				}
+				state.span.End()
+				if duration != nil {
+					duration.Record(ctx, time.Since(state.start).Seconds(), metric.WithAttributes(attrs...))
+				}
+				if retries != nil {
+					retries.Record(ctx, int64(state.retryCount), metric.WithAttributes(attrs...))
+				}
+			}
+			if call != nil {
+				call.SetValue(callStateKey{}, state)
+				call.OnDone(finish)
+			} else {
+				defer func() { finish(nil) }()
+			}
+		}
+
+		retryCount := option.RetryCount(ctx)
+		ctx, span := tracer.Start(
+			trace.ContextWithSpan(ctx, state.span),
+			"HTTP "+req.Method,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("http.request.method", req.Method),
+				attribute.String("url.template", option.PathTemplate(req.URL)),
+				attribute.String("server.address", req.URL.Hostname()),
+				attribute.Int("http.request.resend_count", retryCount),
+			),
+		)
+		defer span.End()
+
+		req = req.WithContext(ctx)
+		c.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+		res, err := next(req)
+
+		state.retryCount = retryCount
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		if res != nil {
+			state.statusCode = res.StatusCode
+			span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+			if res.StatusCode >= 400 {
+				span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+			}
+		}
+		return res, err
+	}
+}
+
+// startCall starts the span of the call which the request is the first attempt
+// of.
+func startCall(tracer trace.Tracer, req *http.Request) *callState {
+	name, ok := operationName(req)
+	if !ok {
+		name = req.Method + " " + option.PathTemplate(req.URL)
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("moderntreasury.operation", name),
+		attribute.String("http.request.method", req.Method),
+	}
+	spanAttrs := attrs
+	if page := option.PageNumber(req.Context()); page > 0 {
+		spanAttrs = append(spanAttrs, attribute.Int("moderntreasury.page", page))
+	} else if strings.HasSuffix(name, ".List") {
+		spanAttrs = append(spanAttrs, attribute.Int("moderntreasury.page", 1))
+	}
+	// The call is internal, so that only its attempts count as client calls.
+	_, span := tracer.Start(req.Context(), name,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(spanAttrs...),
+	)
+	return &callState{span: span, start: time.Now(), attrs: attrs}
+}
@@ -0,0 +1,121 @@
+package otel_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+	"github.com/Modern-Treasury/modern-treasury-go/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// flakyTransport fails the first request it receives with a 503, and responds to
+// the rest with a page which has a next page.
+type flakyTransport struct {
+	attempts int
+}
+
+func (t *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.attempts += 1
+	status := http.StatusOK
+	if t.attempts == 1 {
+		status = http.StatusServiceUnavailable
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header: http.Header{
+			"Content-Type":   []string{"application/json"},
+			"X-After-Cursor": []string{"cursor"},
+		},
+		Body:    io.NopCloser(strings.NewReader(`[{"id":"1"}]`)),
+		Request: req,
+	}, nil
+}
+
+func TestMiddleware(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	metrics := sdkmetric.NewManualReader()
+	client := moderntreasury.NewClient(
+		option.WithBaseURL("http://127.0.0.1:4010"),
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithHTTPClient(&http.Client{Transport: &flakyTransport{}}),
+		option.WithRetryPolicy(option.FullJitterRetryPolicy(time.Millisecond, 2*time.Millisecond)),
+		option.WithMiddleware(otel.Middleware(
+			otel.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+			otel.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(metrics))),
+		)),
+	)
+	iter := client.PaymentOrders.ListAutoPaging(context.Background(), moderntreasury.PaymentOrderListParams{})
+	for i := 0; i < 2 && iter.Next(); i++ {
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 5 {
+		t.Fatalf("expected 5 spans, got %d", len(ended))
+	}
+	var calls []sdktrace.ReadOnlySpan
+	for _, span := range ended {
+		if span.Name() == "PaymentOrderService.List" {
+			calls = append(calls, span)
+		}
+	}
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 call spans, got %d", len(calls))
+	}
+	if got := attr(calls[0], "moderntreasury.retry_count"); got.AsInt64() != 1 {
+		t.Fatalf("expected the first call to have been retried once, got %v", got.AsInt64())
+	}
+	if got := attr(calls[1], "moderntreasury.page"); got.AsInt64() != 2 {
+		t.Fatalf("expected the second call to fetch page 2, got %v", got.AsInt64())
+	}
+	for _, span := range ended {
+		if span.Name() == "HTTP GET" && span.Parent().SpanID() != calls[0].SpanContext().SpanID() && span.Parent().SpanID() != calls[1].SpanContext().SpanID() {
+			t.Fatalf("expected attempt spans to be children of call spans")
+		}
+		// Only the attempts are client spans, so that each request is counted once.
+		want := trace.SpanKindInternal
+		if span.Name() == "HTTP GET" {
+			want = trace.SpanKindClient
+		}
+		if span.SpanKind() != want {
+			t.Fatalf("expected %s to be a %s span, got %s", span.Name(), want, span.SpanKind())
+		}
+	}
+
+	var data metricdata.ResourceMetrics
+	if err := metrics.Collect(context.Background(), &data); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	names := map[string]bool{}
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			names[m.Name] = true
+		}
+	}
+	if !names["moderntreasury.client.call.duration"] || !names["moderntreasury.client.call.retries"] {
+		t.Fatalf("expected call duration and retry histograms, got %v", names)
+	}
+}
+
+func attr(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
@@ -4,11 +4,21 @@ package moderntreasury_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
 	"github.com/Modern-Treasury/modern-treasury-go/internal/testutil"
 	"github.com/Modern-Treasury/modern-treasury-go/option"
+	"github.com/Modern-Treasury/modern-treasury-go/testutil/mtfake"
 )
 
 func TestAutoPagination(t *testing.T) {
@@ -30,3 +40,223 @@ func TestAutoPagination(t *testing.T) {
 		t.Fatalf("err should be nil: %s", err.Error())
 	}
 }
+
+func TestAutoPaginationPrefetch(t *testing.T) {
+	srv := mtfake.NewServer()
+	defer srv.Close()
+	for i := 0; i < 10; i++ {
+		srv.Add("counterparties", mtfake.Object{"name": fmt.Sprintf("counterparty %d", i)})
+	}
+	var requests int64
+	client := moderntreasury.NewClient(append(srv.Options(), option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		atomic.AddInt64(&requests, 1)
+		return next(req)
+	}))...)
+	params := moderntreasury.CounterpartyListParams{PerPage: moderntreasury.F(int64(2))}
+
+	iter := client.Counterparties.ListAutoPaging(context.Background(), params, option.WithPrefetch(2))
+	// The first page, and the two after it as soon as the pager is used.
+	if !iter.Next() {
+		t.Fatalf("expected items, got err %v", iter.Err())
+	}
+	waitForRequests(t, &requests, 3)
+	// The first two pages, and the two after the current one.
+	if !iter.Next() || !iter.Next() {
+		t.Fatalf("expected items, got err %v", iter.Err())
+	}
+	waitForRequests(t, &requests, 4)
+	names := []string{"counterparty 0", "counterparty 1", iter.Current().Name}
+	for iter.Next() {
+		names = append(names, iter.Current().Name)
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	for i, name := range names {
+		if name != fmt.Sprintf("counterparty %d", i) {
+			t.Fatalf("expected items in order, got %v", names)
+		}
+	}
+	if len(names) != 10 {
+		t.Fatalf("expected 10 items, got %v", names)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	iter = client.Counterparties.ListAutoPaging(ctx, params, option.WithPrefetch(2))
+	iter.Next()
+	cancel()
+	for iter.Next() {
+	}
+	if !errors.Is(iter.Err(), context.Canceled) {
+		t.Fatalf("expected the cancellation to stop the pager, got %v", iter.Err())
+	}
+}
+
+// cursorTransport responds to every request with a page of one item, and a
+// cursor to another page.
+type cursorTransport struct {
+	requests int64
+}
+
+func (t *cursorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt64(&t.requests, 1)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}, "X-After-Cursor": {"next"}},
+		Body:       io.NopCloser(strings.NewReader(`[{"id":"cp_123","name":"counterparty"}]`)),
+		Request:    req,
+	}, nil
+}
+
+func TestAutoPaginationPrefetchAbandoned(t *testing.T) {
+	transport := &cursorTransport{}
+	client := moderntreasury.NewClient(
+		option.WithBaseURL("http://127.0.0.1:4010"),
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithHTTPClient(&http.Client{Transport: transport}),
+	)
+	before := runtime.NumGoroutine()
+
+	// A pager which is abandoned without being closed stops fetching once it is
+	// two pages ahead, rather than waiting for its pages to be taken.
+	iter := client.Counterparties.ListAutoPaging(context.Background(), moderntreasury.CounterpartyListParams{}, option.WithPrefetch(2))
+	if !iter.Next() {
+		t.Fatalf("expected items, got err %v", iter.Err())
+	}
+	waitForRequests(t, &transport.requests, 3)
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Fatalf("expected the prefetching goroutine to stop, %d goroutines are left of %d", after, before)
+	}
+}
+
+// waitForRequests waits until n requests were counted, and fails if more were.
+func waitForRequests(t *testing.T, requests *int64, n int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt64(requests) < n && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := atomic.LoadInt64(requests); got != n {
+		t.Fatalf("expected %d requests while prefetching 2 pages, made %d", n, got)
+	}
+}
+
+func TestAutoPaginationIdempotencyKey(t *testing.T) {
+	srv := mtfake.NewServer()
+	defer srv.Close()
+	for i := 0; i < 5; i++ {
+		srv.Add("counterparties", mtfake.Object{"name": fmt.Sprintf("counterparty %d", i)})
+	}
+	var keys []string
+	client := moderntreasury.NewClient(append(srv.Options(), option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		keys = append(keys, req.Header.Get("Idempotency-Key"))
+		return next(req)
+	}))...)
+	params := moderntreasury.CounterpartyListParams{PerPage: moderntreasury.F(int64(2))}
+
+	// The requests of the later pages keep the caller's key.
+	iter := client.Counterparties.ListAutoPaging(context.Background(), params, option.WithIdempotencyKey("export-42"))
+	for iter.Next() {
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if len(keys) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(keys))
+	}
+	for i, key := range keys {
+		if key != "export-42" {
+			t.Fatalf("expected request %d to use the key export-42, got %q", i, key)
+		}
+	}
+}
+
+func TestAutoPaginationCheckpoint(t *testing.T) {
+	srv := mtfake.NewServer()
+	defer srv.Close()
+	for i := 0; i < 5; i++ {
+		srv.Add("counterparties", mtfake.Object{"name": fmt.Sprintf("counterparty %d", i)})
+	}
+	client := moderntreasury.NewClient(srv.Options()...)
+	params := moderntreasury.CounterpartyListParams{PerPage: moderntreasury.F(int64(2))}
+
+	iter := client.Counterparties.ListAutoPaging(context.Background(), params)
+	for i := 0; i < 3 && iter.Next(); i++ {
+	}
+	token, err := json.Marshal(iter.Checkpoint())
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+
+	var checkpoint moderntreasury.Checkpoint
+	if err := json.Unmarshal(token, &checkpoint); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	iter = client.Counterparties.ResumeAutoPaging(context.Background(), checkpoint)
+	var names []string
+	for iter.Next() {
+		names = append(names, iter.Current().Name)
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if len(names) != 2 || names[0] != "counterparty 3" || names[1] != "counterparty 4" || iter.Index() != 5 {
+		t.Fatalf("expected the list to resume after the third item, got %v", names)
+	}
+	if !iter.Checkpoint().Done() {
+		t.Fatal("expected the checkpoint of an exhausted list to be done")
+	}
+
+	events := client.Events.ResumeAutoPaging(context.Background(), checkpoint)
+	if events.Next() || events.Err() == nil {
+		t.Fatal("expected a checkpoint of another list to be rejected")
+	}
+
+	// A resume which fails keeps the checkpoint it started from.
+	failing := moderntreasury.NewClient(append(srv.Options(), option.WithMaxRetries(0), option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		return nil, errors.New("connection refused")
+	}))...)
+	iter = failing.Counterparties.ResumeAutoPaging(context.Background(), checkpoint)
+	if iter.Next() || iter.Err() == nil {
+		t.Fatal("expected the resume to fail")
+	}
+	failed, err := json.Marshal(iter.Checkpoint())
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if iter.Checkpoint().Done() || string(failed) != string(token) {
+		t.Fatalf("expected the failed resume to keep its checkpoint %s, got %s", token, failed)
+	}
+
+	// A list whose first page fails is resumed from its start.
+	iter = failing.Counterparties.ListAutoPaging(context.Background(), params)
+	if iter.Next() || iter.Err() == nil {
+		t.Fatal("expected the first page to fail")
+	}
+	token, err = json.Marshal(iter.Checkpoint())
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if err := json.Unmarshal(token, &checkpoint); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if checkpoint.Done() {
+		t.Fatal("expected the checkpoint of a failed list not to be done")
+	}
+	iter = client.Counterparties.ResumeAutoPaging(context.Background(), checkpoint)
+	names = nil
+	for iter.Next() {
+		names = append(names, iter.Current().Name)
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if len(names) != 5 || names[0] != "counterparty 0" || iter.Index() != 5 {
+		t.Fatalf("expected the list to resume from its start, got %v", names)
+	}
+}
@@ -0,0 +1,262 @@
+package moderntreasury
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+)
+
+// BatchJournal records the items of batches which were created, so that a batch
+// which is run again only submits the items which are missing.
+type BatchJournal interface {
+	// Load returns the IDs of the objects created for the items of the batch, by
+	// the index of the item.
+	Load(ctx context.Context, batchID string) (map[int]string, error)
+	// Record records that the object with the given ID was created for the item of
+	// the batch.
+	Record(ctx context.Context, batchID string, index int, id string) error
+}
+
+// NewMemoryBatchJournal returns a [BatchJournal] which keeps the records in
+// memory.
+func NewMemoryBatchJournal() BatchJournal {
+	return &memoryBatchJournal{batches: map[string]map[int]string{}}
+}
+
+type memoryBatchJournal struct {
+	mu      sync.Mutex
+	batches map[string]map[int]string
+}
+
+func (j *memoryBatchJournal) Load(ctx context.Context, batchID string) (map[int]string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	created := map[int]string{}
+	for index, id := range j.batches[batchID] {
+		created[index] = id
+	}
+	return created, nil
+}
+
+func (j *memoryBatchJournal) Record(ctx context.Context, batchID string, index int, id string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.batches[batchID] == nil {
+		j.batches[batchID] = map[int]string{}
+	}
+	j.batches[batchID][index] = id
+	return nil
+}
+
+// FileBatchJournal is a [BatchJournal] which appends its records to a file, one
+// JSON object per line, so that a batch can be resumed after a crash.
+type FileBatchJournal struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileBatchJournal returns a FileBatchJournal which records to the file at the
+// given path, creating it if it doesn't exist.
+func NewFileBatchJournal(path string) *FileBatchJournal {
+	return &FileBatchJournal{path: path}
+}
+
+type batchJournalRecord struct {
+	BatchID string `json:"batch_id"`
+	Index   int    `json:"index"`
+	ID      string `json:"id"`
+}
+
+func (j *FileBatchJournal) Load(ctx context.Context, batchID string) (map[int]string, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	created := map[int]string{}
+	f, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return created, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record batchJournalRecord
+		// A line which was cut short by a crash is skipped, and its item is
+		// submitted again under the same idempotency key.
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if record.BatchID == batchID {
+			created[record.Index] = record.ID
+		}
+	}
+	return created, scanner.Err()
+}
+
+func (j *FileBatchJournal) Record(ctx context.Context, batchID string, index int, id string) error {
+	line, err := json.Marshal(batchJournalRecord{BatchID: batchID, Index: index, ID: id})
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// BatchOptions configures [PaymentOrderService.NewBatch].
+type BatchOptions struct {
+	// Identifies the batch, e.g. the ID of a payroll run. The idempotency key of
+	// each item is derived from it and the index of the item, so running the same
+	// batch again never creates a payment order twice. Required.
+	BatchID string
+	// The number of payment orders which are created at the same time. Defaults to
+	// 4.
+	Concurrency int
+	// If set, paces the requests of the batch. A limiter which the client was
+	// configured with applies as well.
+	RateLimiter *option.RateLimiter
+	// If set, records the payment orders which were created, so that items which
+	// were created by an earlier run of the batch are skipped.
+	Journal BatchJournal
+}
+
+// BatchItemError is the error of an item of a batch which failed.
+type BatchItemError struct {
+	// The index of the item in the batch.
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("item %d: %s", e.Index, e.Err.Error())
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+// BatchItemResult is the result of an item of a batch.
+type BatchItemResult struct {
+	// The index of the item in the batch.
+	Index int
+	// The ID of the payment order which was created for the item, if any.
+	PaymentOrderID string
+	// The payment order which was created for the item. It is nil when the item
+	// failed, or when it was skipped.
+	PaymentOrder *PaymentOrder
+	// Whether the item was skipped, as the journal recorded that it was created
+	// by an earlier run of the batch.
+	Skipped bool
+	// The error of the item, if it failed.
+	Err *BatchItemError
+}
+
+// BatchResult is the result of a batch, with a result for each of its items in
+// order.
+type BatchResult struct {
+	Items []BatchItemResult
+}
+
+// Errors returns the errors of the items which failed.
+func (r *BatchResult) Errors() []*BatchItemError {
+	var errs []*BatchItemError
+	for _, item := range r.Items {
+		if item.Err != nil {
+			errs = append(errs, item.Err)
+		}
+	}
+	return errs
+}
+
+// NewBatch creates a payment order for each of the params, with bounded
+// concurrency. Each item is sent with an idempotency key derived from the batch
+// ID and its index, and items which the journal recorded as created are skipped,
+// so a batch which was interrupted can be run again to submit the missing items.
+// Items fail independently: NewBatch returns a result for each item, and only
+// returns an error if the journal can't be loaded.
+func (r *PaymentOrderService) NewBatch(ctx context.Context, params []PaymentOrderNewParams, opts BatchOptions, reqOpts ...option.RequestOption) (*BatchResult, error) {
+	if opts.BatchID == "" {
+		return nil, errors.New("a BatchID is required to derive the idempotency keys of the batch")
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 4
+	}
+	if opts.RateLimiter != nil {
+		reqOpts = append([]option.RequestOption{option.WithRateLimiter(opts.RateLimiter)}, reqOpts...)
+	}
+	created := map[int]string{}
+	if opts.Journal != nil {
+		var err error
+		if created, err = opts.Journal.Load(ctx, opts.BatchID); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &BatchResult{Items: make([]BatchItemResult, len(params))}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < opts.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				result.Items[i] = r.newBatchItem(ctx, params[i], i, opts, reqOpts)
+			}
+		}()
+	}
+	for i := range params {
+		if id, ok := created[i]; ok {
+			result.Items[i] = BatchItemResult{Index: i, PaymentOrderID: id, Skipped: true}
+			continue
+		}
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return result, nil
+}
+
+func (r *PaymentOrderService) newBatchItem(ctx context.Context, params PaymentOrderNewParams, index int, opts BatchOptions, reqOpts []option.RequestOption) BatchItemResult {
+	item := BatchItemResult{Index: index}
+	if err := ctx.Err(); err != nil {
+		item.Err = &BatchItemError{Index: index, Err: err}
+		return item
+	}
+	// The options are copied for each item, as the items are created concurrently
+	// and appending to the shared slice would race when it has spare capacity.
+	key := option.DeriveIdempotencyKey("payment_order_batch", opts.BatchID+"\x00"+strconv.Itoa(index), 0)
+	itemOpts := append(append(make([]option.RequestOption, 0, len(reqOpts)+1), reqOpts...), option.WithIdempotencyKey(key))
+	paymentOrder, err := r.New(ctx, params, itemOpts...)
+	if err != nil {
+		item.Err = &BatchItemError{Index: index, Err: err}
+		return item
+	}
+	item.PaymentOrder = paymentOrder
+	item.PaymentOrderID = paymentOrder.ID
+	if opts.Journal != nil {
+		if err := opts.Journal.Record(ctx, opts.BatchID, index, paymentOrder.ID); err != nil {
+			item.Err = &BatchItemError{Index: index, Err: fmt.Errorf("payment order %s was created, but not recorded in the journal: %w", paymentOrder.ID, err)}
+		}
+	}
+	return item
+}
@@ -0,0 +1,147 @@
+package moderntreasury_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+	"github.com/Modern-Treasury/modern-treasury-go/testutil/mtfake"
+)
+
+func TestPaymentOrderNewBatch(t *testing.T) {
+	srv := mtfake.NewServer()
+	defer srv.Close()
+
+	// The payment order of 1300 is rejected until fail is unset, and the
+	// idempotency keys of the requests are collected.
+	var mu sync.Mutex
+	fail := true
+	keys := map[string]bool{}
+	client := moderntreasury.NewClient(append(srv.Options(), option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		mu.Lock()
+		defer mu.Unlock()
+		keys[req.Header.Get("Idempotency-Key")] = true
+		if fail && strings.Contains(string(body), "name=\"amount\"\r\n\r\n1300\r\n") {
+			return &http.Response{
+				StatusCode: http.StatusUnprocessableEntity,
+				Header:     http.Header{"Content-Type": {"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(`{"errors":{"code":"parameter_invalid","message":"Amount is invalid","parameter":"amount"}}`)),
+				Request:    req,
+			}, nil
+		}
+		return next(req)
+	}))...)
+	ctx := context.Background()
+
+	var params []moderntreasury.PaymentOrderNewParams
+	for i := 0; i < 5; i++ {
+		params = append(params, moderntreasury.PaymentOrderNewParams{
+			Amount:               moderntreasury.F(int64(1000 + 100*i)),
+			Direction:            moderntreasury.F(moderntreasury.PaymentOrderNewParamsDirectionCredit),
+			OriginatingAccountID: moderntreasury.F("0f8e3719-3dfd-4613-9bbf-c0333781b59f"),
+			Type:                 moderntreasury.F(moderntreasury.PaymentOrderTypeACH),
+		})
+	}
+	opts := moderntreasury.BatchOptions{
+		BatchID:     "payroll-2023-05",
+		Concurrency: 3,
+		RateLimiter: option.NewRateLimiter(1000, 5),
+		Journal:     moderntreasury.NewFileBatchJournal(filepath.Join(t.TempDir(), "journal.jsonl")),
+	}
+
+	result, err := client.PaymentOrders.NewBatch(ctx, params, opts)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	errs := result.Errors()
+	if len(errs) != 1 || errs[0].Index != 3 || !moderntreasury.IsValidationError(errs[0]) {
+		t.Fatalf("expected item 3 to fail validation, got %v", errs)
+	}
+	for i, item := range result.Items {
+		if i != 3 && (item.PaymentOrder == nil || item.PaymentOrder.Amount != int64(1000+100*i)) {
+			t.Fatalf("unexpected result of item %d: %+v", i, item)
+		}
+	}
+	if len(keys) != 5 {
+		t.Fatalf("expected 5 distinct idempotency keys, got %d", len(keys))
+	}
+
+	// Running the batch again only submits the item which failed, under the same
+	// idempotency key.
+	fail = false
+	result, err = client.PaymentOrders.NewBatch(ctx, params, opts)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if errs := result.Errors(); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+	for i, item := range result.Items {
+		if item.Skipped != (i != 3) || item.PaymentOrderID == "" {
+			t.Fatalf("unexpected result of item %d: %+v", i, item)
+		}
+	}
+	if len(keys) != 5 {
+		t.Fatalf("expected the retry to reuse its idempotency key, got %d keys", len(keys))
+	}
+}
+
+func TestPaymentOrderNewBatchSharedOptions(t *testing.T) {
+	srv := mtfake.NewServer()
+	defer srv.Close()
+
+	// The idempotency key of each request is collected by the amount of its
+	// payment order.
+	var mu sync.Mutex
+	keys := map[string]string{}
+	client := moderntreasury.NewClient(srv.Options()...)
+	reqOpts := make([]option.RequestOption, 0, 8)
+	reqOpts = append(reqOpts, option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		for i := 0; i < 20; i++ {
+			if strings.Contains(string(body), fmt.Sprintf("name=\"amount\"\r\n\r\n%d\r\n", 1000+i)) {
+				mu.Lock()
+				keys[strconv.Itoa(i)] = req.Header.Get("Idempotency-Key")
+				mu.Unlock()
+			}
+		}
+		return next(req)
+	}))
+
+	var params []moderntreasury.PaymentOrderNewParams
+	for i := 0; i < 20; i++ {
+		params = append(params, moderntreasury.PaymentOrderNewParams{
+			Amount:               moderntreasury.F(int64(1000 + i)),
+			Direction:            moderntreasury.F(moderntreasury.PaymentOrderNewParamsDirectionCredit),
+			OriginatingAccountID: moderntreasury.F("0f8e3719-3dfd-4613-9bbf-c0333781b59f"),
+			Type:                 moderntreasury.F(moderntreasury.PaymentOrderTypeACH),
+		})
+	}
+	opts := moderntreasury.BatchOptions{BatchID: "payroll-2023-06", Concurrency: 8}
+	result, err := client.PaymentOrders.NewBatch(context.Background(), params, opts, reqOpts...)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if errs := result.Errors(); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+	// Each item is sent with the key derived from its own index.
+	for i := 0; i < 20; i++ {
+		want := option.DeriveIdempotencyKey("payment_order_batch", opts.BatchID+"\x00"+strconv.Itoa(i), 0)
+		if got := keys[strconv.Itoa(i)]; got != want {
+			t.Fatalf("expected item %d to be sent with key %s, got %s", i, want, got)
+		}
+	}
+}
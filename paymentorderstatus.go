@@ -0,0 +1,240 @@
+package moderntreasury
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+)
+
+// paymentOrderTransitions are the statuses which a payment order in each status
+// can move to.
+var paymentOrderTransitions = map[PaymentOrderStatus][]PaymentOrderStatus{
+	PaymentOrderStatusNeedsApproval: {PaymentOrderStatusApproved, PaymentOrderStatusDenied, PaymentOrderStatusCancelled},
+	PaymentOrderStatusPending:       {PaymentOrderStatusApproved, PaymentOrderStatusProcessing, PaymentOrderStatusCancelled, PaymentOrderStatusFailed},
+	PaymentOrderStatusApproved:      {PaymentOrderStatusProcessing, PaymentOrderStatusSent, PaymentOrderStatusCompleted, PaymentOrderStatusCancelled, PaymentOrderStatusFailed},
+	PaymentOrderStatusProcessing:    {PaymentOrderStatusSent, PaymentOrderStatusCompleted, PaymentOrderStatusCancelled, PaymentOrderStatusFailed},
+	PaymentOrderStatusSent:          {PaymentOrderStatusCompleted, PaymentOrderStatusFailed, PaymentOrderStatusReturned},
+	PaymentOrderStatusCompleted:     {PaymentOrderStatusReturned, PaymentOrderStatusReversed},
+	PaymentOrderStatusCancelled:     {},
+	PaymentOrderStatusDenied:        {},
+	PaymentOrderStatusFailed:        {},
+	PaymentOrderStatusReturned:      {},
+	PaymentOrderStatusReversed:      {},
+}
+
+// IsTerminal reports whether a payment order in the status can't change status
+// any more: it was cancelled, denied, failed, returned or reversed. A completed
+// payment order is not terminal, as it may still be returned or reversed.
+func (r PaymentOrderStatus) IsTerminal() bool {
+	next, ok := paymentOrderTransitions[r]
+	return ok && len(next) == 0
+}
+
+// CanTransitionTo reports whether a payment order in the status can move to the
+// other status directly. Statuses which the SDK doesn't know can transition to
+// any other status.
+func (r PaymentOrderStatus) CanTransitionTo(status PaymentOrderStatus) bool {
+	next, ok := paymentOrderTransitions[r]
+	if !ok {
+		return r != status
+	}
+	for _, s := range next {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// canReach reports whether a payment order in the status can reach any of the
+// statuses, directly or through other statuses.
+func (r PaymentOrderStatus) canReach(statuses []PaymentOrderStatus) bool {
+	if _, ok := paymentOrderTransitions[r]; !ok {
+		return true
+	}
+	seen := map[PaymentOrderStatus]bool{r: true}
+	queue := []PaymentOrderStatus{r}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range paymentOrderTransitions[current] {
+			for _, s := range statuses {
+				if next == s {
+					return true
+				}
+			}
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return false
+}
+
+// PaymentOrderStatusError is returned by [PaymentOrderService.WaitForStatus] when
+// the payment order reached a status from which it can't reach any of the
+// statuses which were waited for, e.g. when it failed, was returned or was
+// cancelled.
+type PaymentOrderStatusError struct {
+	// The payment order, in the status it ended in.
+	PaymentOrder *PaymentOrder
+	// The statuses which were waited for.
+	Want []PaymentOrderStatus
+}
+
+func (e *PaymentOrderStatusError) Error() string {
+	want := make([]string, len(e.Want))
+	for i, s := range e.Want {
+		want[i] = string(s)
+	}
+	return fmt.Sprintf("payment order %s is %s, and can't become %s", e.PaymentOrder.ID, e.PaymentOrder.Status, strings.Join(want, " or "))
+}
+
+// PaymentOrderUpdates is a source of updates of payment orders, such as
+// webhooks, which [PaymentOrderService.WaitForStatus] listens to.
+type PaymentOrderUpdates interface {
+	// Subscribe returns a channel of the updates of the payment order, and a
+	// function which ends the subscription.
+	Subscribe(id string) (updates <-chan *PaymentOrder, cancel func())
+}
+
+// PaymentOrderFeed is a [PaymentOrderUpdates] to which updates are published,
+// e.g. from the OnPaymentOrder callback of a [WebhookHandler]:
+//
+//	feed := moderntreasury.NewPaymentOrderFeed()
+//	handler.OnPaymentOrder(func(ctx context.Context, event moderntreasury.WebhookEvent, paymentOrder *moderntreasury.PaymentOrder) error {
+//		feed.Publish(paymentOrder)
+//		return nil
+//	})
+type PaymentOrderFeed struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan *PaymentOrder]struct{}
+}
+
+// NewPaymentOrderFeed returns an empty [PaymentOrderFeed].
+func NewPaymentOrderFeed() *PaymentOrderFeed {
+	return &PaymentOrderFeed{subscribers: map[string]map[chan *PaymentOrder]struct{}{}}
+}
+
+// Publish sends the update to the subscribers of the payment order. Subscribers
+// which haven't received the previous update yet miss it, as only the latest
+// update matters.
+func (f *PaymentOrderFeed) Publish(paymentOrder *PaymentOrder) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.subscribers[paymentOrder.ID] {
+		select {
+		case ch <- paymentOrder:
+		default:
+		}
+	}
+}
+
+func (f *PaymentOrderFeed) Subscribe(id string) (<-chan *PaymentOrder, func()) {
+	ch := make(chan *PaymentOrder, 1)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.subscribers[id] == nil {
+		f.subscribers[id] = map[chan *PaymentOrder]struct{}{}
+	}
+	f.subscribers[id][ch] = struct{}{}
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subscribers[id], ch)
+		if len(f.subscribers[id]) == 0 {
+			delete(f.subscribers, id)
+		}
+	}
+}
+
+// PaymentOrderWaitOptions configures [PaymentOrderService.WaitForStatus].
+type PaymentOrderWaitOptions struct {
+	// The delay before the second poll, which doubles after each poll. Defaults to
+	// 1 second.
+	Interval time.Duration
+	// The longest delay between polls. Defaults to 30 seconds.
+	MaxInterval time.Duration
+	// If set, the longest time to wait. Otherwise the wait is bounded by the
+	// context only.
+	Timeout time.Duration
+	// If set, updates of the payment order are taken from the source as soon as
+	// they are received. The payment order is still polled, in case an update is
+	// missed.
+	Updates PaymentOrderUpdates
+}
+
+// WaitForStatus waits until the payment order reaches one of the statuses, and
+// returns it. It polls the payment order with an exponential backoff, and
+// listens to the Updates of the options if set. If the payment order reaches a
+// status from which it can't reach any of the statuses, e.g. when it fails, is
+// returned or is cancelled, it returns a [*PaymentOrderStatusError].
+func (r *PaymentOrderService) WaitForStatus(ctx context.Context, id string, statuses []PaymentOrderStatus, opts PaymentOrderWaitOptions, reqOpts ...option.RequestOption) (*PaymentOrder, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.MaxInterval <= 0 {
+		opts.MaxInterval = 30 * time.Second
+	}
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	var updates <-chan *PaymentOrder
+	if opts.Updates != nil {
+		var unsubscribe func()
+		updates, unsubscribe = opts.Updates.Subscribe(id)
+		defer unsubscribe()
+	}
+
+	check := func(paymentOrder *PaymentOrder) (bool, error) {
+		for _, s := range statuses {
+			if paymentOrder.Status == s {
+				return true, nil
+			}
+		}
+		if !paymentOrder.Status.canReach(statuses) {
+			return true, &PaymentOrderStatusError{PaymentOrder: paymentOrder, Want: statuses}
+		}
+		return false, nil
+	}
+
+	interval := opts.Interval
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case paymentOrder := <-updates:
+			if done, err := check(paymentOrder); done {
+				if err != nil {
+					return nil, err
+				}
+				return paymentOrder, nil
+			}
+		case <-timer.C:
+			paymentOrder, err := r.Get(ctx, id, reqOpts...)
+			if err != nil {
+				return nil, err
+			}
+			if done, err := check(paymentOrder); done {
+				if err != nil {
+					return nil, err
+				}
+				return paymentOrder, nil
+			}
+			timer.Reset(interval)
+			interval *= 2
+			if interval > opts.MaxInterval {
+				interval = opts.MaxInterval
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package moderntreasury_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+	"github.com/Modern-Treasury/modern-treasury-go/testutil/mtfake"
+)
+
+func TestPaymentOrderStatusTransitions(t *testing.T) {
+	if !moderntreasury.PaymentOrderStatusFailed.IsTerminal() || moderntreasury.PaymentOrderStatusCompleted.IsTerminal() {
+		t.Fatalf("expected failed to be terminal, and completed not to be")
+	}
+	if !moderntreasury.PaymentOrderStatusSent.CanTransitionTo(moderntreasury.PaymentOrderStatusReturned) {
+		t.Fatalf("expected a sent payment order to be able to be returned")
+	}
+	if moderntreasury.PaymentOrderStatusCancelled.CanTransitionTo(moderntreasury.PaymentOrderStatusApproved) {
+		t.Fatalf("expected a cancelled payment order not to be able to be approved")
+	}
+}
+
+func TestPaymentOrderWaitForStatus(t *testing.T) {
+	srv := mtfake.NewServer()
+	defer srv.Close()
+	client := moderntreasury.NewClient(srv.Options()...)
+	ctx := context.Background()
+	completed := []moderntreasury.PaymentOrderStatus{moderntreasury.PaymentOrderStatusCompleted}
+
+	setStatus := func(id string, statuses ...moderntreasury.PaymentOrderUpdateParamsStatus) {
+		for _, status := range statuses {
+			time.Sleep(10 * time.Millisecond)
+			if _, err := client.PaymentOrders.Update(ctx, id, moderntreasury.PaymentOrderUpdateParams{Status: moderntreasury.F(status)}); err != nil {
+				t.Errorf("err should be nil: %s", err.Error())
+			}
+		}
+	}
+
+	// Polling until the payment order completes.
+	paymentOrder := srv.Add("payment_orders", mtfake.Object{"amount": 1000, "status": "approved"})
+	id := paymentOrder["id"].(string)
+	go setStatus(id, moderntreasury.PaymentOrderUpdateParamsStatusProcessing, moderntreasury.PaymentOrderUpdateParamsStatusCompleted)
+	res, err := client.PaymentOrders.WaitForStatus(ctx, id, completed, moderntreasury.PaymentOrderWaitOptions{
+		Interval:    time.Millisecond,
+		MaxInterval: 5 * time.Millisecond,
+		Timeout:     5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if res.Status != moderntreasury.PaymentOrderStatusCompleted {
+		t.Fatalf("expected a completed payment order, got %s", res.Status)
+	}
+
+	// A failed payment order can't complete.
+	paymentOrder = srv.Add("payment_orders", mtfake.Object{"amount": 1000, "status": "processing"})
+	id = paymentOrder["id"].(string)
+	go setStatus(id, moderntreasury.PaymentOrderUpdateParamsStatusFailed)
+	_, err = client.PaymentOrders.WaitForStatus(ctx, id, completed, moderntreasury.PaymentOrderWaitOptions{
+		Interval: time.Millisecond,
+		Timeout:  5 * time.Second,
+	})
+	var statusErr *moderntreasury.PaymentOrderStatusError
+	if !errors.As(err, &statusErr) || statusErr.PaymentOrder.Status != moderntreasury.PaymentOrderStatusFailed {
+		t.Fatalf("expected a PaymentOrderStatusError of a failed payment order, got %v", err)
+	}
+
+	// Updates are taken from the feed without waiting for the next poll.
+	paymentOrder = srv.Add("payment_orders", mtfake.Object{"amount": 1000, "status": "sent"})
+	id = paymentOrder["id"].(string)
+	feed := moderntreasury.NewPaymentOrderFeed()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		feed.Publish(&moderntreasury.PaymentOrder{ID: id, Status: moderntreasury.PaymentOrderStatusCompleted})
+	}()
+	res, err = client.PaymentOrders.WaitForStatus(ctx, id, completed, moderntreasury.PaymentOrderWaitOptions{
+		Interval: time.Hour,
+		Timeout:  5 * time.Second,
+		Updates:  feed,
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if res.Status != moderntreasury.PaymentOrderStatusCompleted {
+		t.Fatalf("expected a completed payment order, got %s", res.Status)
+	}
+}
@@ -0,0 +1,243 @@
+package moderntreasury
+
+import (
+	"context"
+
+	"github.com/Modern-Treasury/modern-treasury-go/internal/shared"
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+)
+
+// Checkpoint is a position in a paginated list, from which the list can be
+// resumed with the ResumeAutoPaging method of its service.
+type Checkpoint = shared.Checkpoint
+
+// Resume listing account collection flows from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *AccountCollectionFlowService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[AccountCollectionFlow] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[AccountCollectionFlow](ctx, "api/account_collection_flows", checkpoint, opts...)
+}
+
+// Resume listing account details from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *AccountDetailService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[AccountDetail] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[AccountDetail](ctx, "api/*/*/account_details", checkpoint, opts...)
+}
+
+// Resume listing balance reports from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *InternalAccountBalanceReportService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[BalanceReport] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[BalanceReport](ctx, "api/internal_accounts/*/balance_reports", checkpoint, opts...)
+}
+
+// Resume listing connections from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *ConnectionService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[Connection] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[Connection](ctx, "api/connections", checkpoint, opts...)
+}
+
+// Resume listing counterparties from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *CounterpartyService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[Counterparty] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[Counterparty](ctx, "api/counterparties", checkpoint, opts...)
+}
+
+// Resume listing documents from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *DocumentService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[Document] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[Document](ctx, "api/documents", checkpoint, opts...)
+}
+
+// Resume listing events from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *EventService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[Event] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[Event](ctx, "api/events", checkpoint, opts...)
+}
+
+// Resume listing expected payments from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *ExpectedPaymentService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[ExpectedPayment] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[ExpectedPayment](ctx, "api/expected_payments", checkpoint, opts...)
+}
+
+// Resume listing external accounts from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *ExternalAccountService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[ExternalAccount] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[ExternalAccount](ctx, "api/external_accounts", checkpoint, opts...)
+}
+
+// Resume listing incoming payment details from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *IncomingPaymentDetailService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[IncomingPaymentDetail] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[IncomingPaymentDetail](ctx, "api/incoming_payment_details", checkpoint, opts...)
+}
+
+// Resume listing internal accounts from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *InternalAccountService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[InternalAccount] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[InternalAccount](ctx, "api/internal_accounts", checkpoint, opts...)
+}
+
+// Resume listing invoices from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *InvoiceService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[Invoice] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[Invoice](ctx, "api/invoices", checkpoint, opts...)
+}
+
+// Resume listing invoice line items from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *InvoiceLineItemService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[InvoiceLineItem] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[InvoiceLineItem](ctx, "api/invoices/*/invoice_line_items", checkpoint, opts...)
+}
+
+// Resume listing ledgers from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *LedgerService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[Ledger] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[Ledger](ctx, "api/ledgers", checkpoint, opts...)
+}
+
+// Resume listing ledger accounts from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *LedgerAccountService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[LedgerAccount] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[LedgerAccount](ctx, "api/ledger_accounts", checkpoint, opts...)
+}
+
+// Resume listing ledger account balance monitors from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *LedgerAccountBalanceMonitorService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[LedgerAccountBalanceMonitor] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[LedgerAccountBalanceMonitor](ctx, "api/ledger_account_balance_monitors", checkpoint, opts...)
+}
+
+// Resume listing ledger account categories from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *LedgerAccountCategoryService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[LedgerAccountCategory] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[LedgerAccountCategory](ctx, "api/ledger_account_categories", checkpoint, opts...)
+}
+
+// Resume listing ledger account payouts from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *LedgerAccountPayoutService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[LedgerAccountPayout] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[LedgerAccountPayout](ctx, "api/ledger_account_payouts", checkpoint, opts...)
+}
+
+// Resume listing ledger entries from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *LedgerEntryService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[LedgerEntry] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[LedgerEntry](ctx, "api/ledger_entries", checkpoint, opts...)
+}
+
+// Resume listing ledger event handlers from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *LedgerEventHandlerService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[LedgerEventHandlerListResponse] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[LedgerEventHandlerListResponse](ctx, "api/ledger_event_handlers", checkpoint, opts...)
+}
+
+// Resume listing ledger transactions from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *LedgerTransactionService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[LedgerTransaction] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[LedgerTransaction](ctx, "api/ledger_transactions", checkpoint, opts...)
+}
+
+// Resume listing ledger transaction versions from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *LedgerTransactionVersionService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[LedgerTransactionVersion] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[LedgerTransactionVersion](ctx, "api/ledger_transaction_versions", checkpoint, opts...)
+}
+
+// Resume listing line items from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *LineItemService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[LineItem] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[LineItem](ctx, "api/*/*/line_items", checkpoint, opts...)
+}
+
+// Resume listing paper items from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *PaperItemService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[PaperItem] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[PaperItem](ctx, "api/paper_items", checkpoint, opts...)
+}
+
+// Resume listing payment flows from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *PaymentFlowService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[PaymentFlow] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[PaymentFlow](ctx, "api/payment_flows", checkpoint, opts...)
+}
+
+// Resume listing payment orders from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *PaymentOrderService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[PaymentOrder] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[PaymentOrder](ctx, "api/payment_orders", checkpoint, opts...)
+}
+
+// Resume listing reversals from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *PaymentOrderReversalService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[Reversal] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[Reversal](ctx, "api/payment_orders/*/reversals", checkpoint, opts...)
+}
+
+// Resume listing payment references from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *PaymentReferenceService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[PaymentReference] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[PaymentReference](ctx, "api/payment_references", checkpoint, opts...)
+}
+
+// Resume listing returns from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *ReturnService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[ReturnObject] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[ReturnObject](ctx, "api/returns", checkpoint, opts...)
+}
+
+// Resume listing routing details from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *RoutingDetailService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[RoutingDetail] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[RoutingDetail](ctx, "api/*/*/routing_details", checkpoint, opts...)
+}
+
+// Resume listing transactions from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *TransactionService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[Transaction] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[Transaction](ctx, "api/transactions", checkpoint, opts...)
+}
+
+// Resume listing transaction line items from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *TransactionLineItemService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[TransactionLineItem] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[TransactionLineItem](ctx, "api/transaction_line_items", checkpoint, opts...)
+}
+
+// Resume listing virtual accounts from a checkpoint taken with Checkpoint on a page
+// or auto-pager of an earlier list.
+func (r *VirtualAccountService) ResumeAutoPaging(ctx context.Context, checkpoint shared.Checkpoint, opts ...option.RequestOption) *shared.PageAutoPager[VirtualAccount] {
+	opts = append(r.Options, opts...)
+	return shared.ResumeAutoPager[VirtualAccount](ctx, "api/virtual_accounts", checkpoint, opts...)
+}
@@ -0,0 +1,233 @@
+package moderntreasury
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Pager is the interface of the auto-pagers returned by the ListAutoPaging
+// methods of the services.
+type Pager[T any] interface {
+	Next() bool
+	Current() T
+	Err() error
+}
+
+// ScanParams configures a [Scanner].
+type ScanParams[T any] struct {
+	// The time range to scan, which is split into Shards ranges of equal length.
+	Start time.Time
+	End   time.Time
+	// The number of ranges to split the time range into, and list concurrently.
+	// Defaults to 1.
+	Shards int
+	// If set, the boundaries between ranges are truncated to a multiple of it,
+	// e.g. to a day for lists which are filtered by date.
+	Granularity time.Duration
+	// Whether the items of a range are returned before those of the ranges after
+	// it. Otherwise, the items of all ranges are returned as they are listed.
+	Ordered bool
+	// The number of items which are listed ahead of the scan for each range.
+	// Defaults to 100.
+	Buffer int
+	// ID returns the ID of an item, and Time the time which the list's time bounds
+	// apply to. Items which are listed in more than one range, because the bounds
+	// of the list are inclusive, are only returned once. Only the IDs of items
+	// within a second, or the Granularity, of a boundary between ranges are kept
+	// for this, so the scan's memory doesn't grow with its items. If ID is nil,
+	// items are not de-duplicated; otherwise Time is required.
+	ID   func(item T) string
+	Time func(item T) time.Time
+	// List returns an auto-pager over the items in the range from start to end, as
+	// it is given to the list's time bounds, e.g.
+	//
+	//	func(ctx context.Context, start, end time.Time) moderntreasury.Pager[moderntreasury.Event] {
+	//		return client.Events.ListAutoPaging(ctx, moderntreasury.EventListParams{
+	//			EventTimeStart: moderntreasury.F(start),
+	//			EventTimeEnd:   moderntreasury.F(end),
+	//		})
+	//	}
+	List func(ctx context.Context, start time.Time, end time.Time) Pager[T]
+}
+
+// Scanner lists the items of a large time range, by splitting it into shorter
+// ranges which are paged concurrently, each with its own cursor, and merging
+// their items into one stream. It is used like the auto-pagers of the services.
+type Scanner[T any] struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	id     func(item T) string
+	time   func(item T) time.Time
+	// The boundaries between ranges, and how close to one an item must be to be
+	// de-duplicated.
+	bounds []time.Time
+	margin time.Duration
+	// When ordered, each range sends its items on its own channel, which are read
+	// in turn; otherwise all ranges share one channel.
+	results []chan scanResult[T]
+	idx     int
+	seen    map[string]struct{}
+	cur     T
+	err     error
+}
+
+type scanResult[T any] struct {
+	item T
+	err  error
+}
+
+// NewScanner starts listing the ranges of the scan in the background, and returns
+// a Scanner over their items. The scanner should be closed with Close if it is
+// abandoned before it is exhausted.
+func NewScanner[T any](ctx context.Context, params ScanParams[T]) *Scanner[T] {
+	if params.Shards < 1 {
+		params.Shards = 1
+	}
+	if params.Buffer < 1 {
+		params.Buffer = 100
+	}
+	s := &Scanner[T]{ctx: ctx, id: params.ID, time: params.Time}
+	if params.List == nil || !params.Start.Before(params.End) {
+		s.err = errors.New("moderntreasury: a scan needs a List function and a start before its end")
+		return s
+	}
+	if params.ID != nil && params.Time == nil {
+		s.err = errors.New("moderntreasury: a scan which de-duplicates items by ID needs their Time")
+		return s
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	bounds := shardBounds(params.Start, params.End, params.Shards, params.Granularity)
+	if s.id != nil {
+		s.seen = map[string]struct{}{}
+		s.bounds = bounds[1 : len(bounds)-1]
+		s.margin = time.Second
+		if params.Granularity > s.margin {
+			s.margin = params.Granularity
+		}
+	}
+	merged := make(chan scanResult[T], params.Buffer)
+	var wg sync.WaitGroup
+	for i := 0; i < len(bounds)-1; i++ {
+		out := merged
+		if params.Ordered {
+			out = make(chan scanResult[T], params.Buffer)
+			s.results = append(s.results, out)
+		}
+		wg.Add(1)
+		go func(start, end time.Time, out chan<- scanResult[T]) {
+			defer wg.Done()
+			if params.Ordered {
+				defer close(out)
+			}
+			listRange(ctx, params.List(ctx, start, end), out)
+		}(bounds[i], bounds[i+1], out)
+	}
+	if !params.Ordered {
+		s.results = []chan scanResult[T]{merged}
+		go func() {
+			wg.Wait()
+			close(merged)
+		}()
+	}
+	return s
+}
+
+// listRange sends the items of a range, and then its error if it has one, until
+// the context is done.
+func listRange[T any](ctx context.Context, pager Pager[T], out chan<- scanResult[T]) {
+	if closer, ok := pager.(interface{ Close() }); ok {
+		defer closer.Close()
+	}
+	for pager.Next() {
+		select {
+		case out <- scanResult[T]{item: pager.Current()}:
+		case <-ctx.Done():
+			return
+		}
+	}
+	if err := pager.Err(); err != nil {
+		select {
+		case out <- scanResult[T]{err: err}:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// shardBounds returns the boundaries of the ranges which the time range is split
+// into, including its start and end.
+func shardBounds(start time.Time, end time.Time, shards int, granularity time.Duration) []time.Time {
+	bounds := []time.Time{start}
+	step := end.Sub(start) / time.Duration(shards)
+	for i := 1; i < shards; i++ {
+		bound := start.Add(step * time.Duration(i))
+		if granularity > 0 {
+			bound = bound.Truncate(granularity)
+		}
+		// Ranges which are empty after truncation are skipped.
+		if bound.After(bounds[len(bounds)-1]) && bound.Before(end) {
+			bounds = append(bounds, bound)
+		}
+	}
+	return append(bounds, end)
+}
+
+// Next advances the scanner to the next item, and reports whether there is one.
+func (s *Scanner[T]) Next() bool {
+	for s.err == nil && s.idx < len(s.results) {
+		result, ok := <-s.results[s.idx]
+		if !ok {
+			s.idx++
+			continue
+		}
+		if result.err != nil {
+			s.err = result.err
+			break
+		}
+		if s.seen != nil && s.nearBoundary(s.time(result.item)) {
+			id := s.id(result.item)
+			if _, ok := s.seen[id]; ok {
+				continue
+			}
+			s.seen[id] = struct{}{}
+		}
+		s.cur = result.item
+		return true
+	}
+	// The ranges stop without an error once the scan's context is done.
+	if s.err == nil {
+		s.err = s.ctx.Err()
+	}
+	s.Close()
+	return false
+}
+
+// nearBoundary reports whether an item at the time may be listed by the ranges on
+// both sides of a boundary.
+func (s *Scanner[T]) nearBoundary(t time.Time) bool {
+	for _, bound := range s.bounds {
+		if d := t.Sub(bound); d >= -s.margin && d <= s.margin {
+			return true
+		}
+	}
+	return false
+}
+
+// Current returns the item which the scanner is at.
+func (s *Scanner[T]) Current() T {
+	return s.cur
+}
+
+// Err returns the error which stopped the scanner, if any.
+func (s *Scanner[T]) Err() error {
+	return s.err
+}
+
+// Close stops listing the ranges of the scan.
+func (s *Scanner[T]) Close() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
@@ -0,0 +1,88 @@
+package moderntreasury_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+	"github.com/Modern-Treasury/modern-treasury-go/testutil/mtfake"
+)
+
+func TestScanner(t *testing.T) {
+	srv := mtfake.NewServer()
+	defer srv.Close()
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 48; i++ {
+		srv.Add("events", mtfake.Object{
+			"event_name": "created",
+			"event_time": start.Add(time.Duration(i) * time.Hour).Format(time.RFC3339),
+		})
+	}
+	client := moderntreasury.NewClient(srv.Options()...)
+	params := moderntreasury.ScanParams[moderntreasury.Event]{
+		Start:   start,
+		End:     start.Add(48 * time.Hour),
+		Shards:  4,
+		Ordered: true,
+		ID:      func(event moderntreasury.Event) string { return event.ID },
+		Time:    func(event moderntreasury.Event) time.Time { return event.EventTime },
+		List: func(ctx context.Context, start, end time.Time) moderntreasury.Pager[moderntreasury.Event] {
+			return client.Events.ListAutoPaging(ctx, moderntreasury.EventListParams{
+				EventTimeStart: moderntreasury.F(start),
+				EventTimeEnd:   moderntreasury.F(end),
+				PerPage:        moderntreasury.F(int64(5)),
+			})
+		},
+	}
+
+	scanner := moderntreasury.NewScanner(context.Background(), params)
+	var times []time.Time
+	for scanner.Next() {
+		times = append(times, scanner.Current().EventTime)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	// The bounds are inclusive, so the events at the boundaries of the shards are
+	// listed twice, but only returned once.
+	if len(times) != 48 {
+		t.Fatalf("expected 48 events, got %d", len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		if !times[i].After(times[i-1]) {
+			t.Fatalf("expected ordered events, got %v after %v", times[i], times[i-1])
+		}
+	}
+
+	params.Ordered = false
+	scanner = moderntreasury.NewScanner(context.Background(), params)
+	count := 0
+	for scanner.Next() {
+		count++
+	}
+	if scanner.Err() != nil || count != 48 {
+		t.Fatalf("expected 48 unordered events, got %d, %v", count, scanner.Err())
+	}
+
+	noTime := params
+	noTime.Time = nil
+	if scanner = moderntreasury.NewScanner(context.Background(), noTime); scanner.Next() || scanner.Err() == nil {
+		t.Fatal("expected a scan which de-duplicates by ID to need the time of items")
+	}
+
+	client = moderntreasury.NewClient(append(srv.Options(), option.WithMaxRetries(0), option.WithBaseURL("http://127.0.0.1:1"))...)
+	scanner = moderntreasury.NewScanner(context.Background(), params)
+	if scanner.Next() || scanner.Err() == nil {
+		t.Fatal("expected the error of a shard to stop the scan")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	scanner = moderntreasury.NewScanner(ctx, params)
+	if scanner.Next() || !errors.Is(scanner.Err(), context.Canceled) {
+		t.Fatalf("expected the scan to stop when its context is done, got %v", scanner.Err())
+	}
+}
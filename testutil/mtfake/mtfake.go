@@ -0,0 +1,668 @@
+// Package mtfake provides an in-process fake of the Modern Treasury API, for tests
+// which exercise stateful flows, such as creating, updating and listing objects,
+// without a mock server running against the OpenAPI spec.
+//
+// It complements rather than replaces the Prism mock server which the generated
+// tests of each endpoint, guarded by internal/testutil.CheckTestServer, run
+// against: those tests send example parameters, such as IDs of objects which
+// don't exist, which the fake rejects as a real API would.
+//
+//	srv := mtfake.NewServer()
+//	defer srv.Close()
+//	client := moderntreasury.NewClient(srv.Options()...)
+//
+// The fake keeps its objects in memory and serves any resource of the API
+// generically: a POST to a collection creates an object with a UUID and
+// timestamps, objects are fetched, updated and deleted by ID, and collections are
+// listed in the order their objects were created, with `X-After-Cursor`
+// pagination and filtering on the top-level fields, metadata and time ranges of
+// the objects.
+// Creating, updating and deleting top-level objects also records events, which
+// are listed by the Events API.
+package mtfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+	"github.com/google/uuid"
+)
+
+// Object is an object of the API, as it is encoded in JSON.
+type Object = map[string]interface{}
+
+// collections are the path segments which name collections of objects. Every
+// other path segment is taken to be an ID.
+var collections = map[string]bool{
+	"account_collection_flows":        true,
+	"account_details":                 true,
+	"balance_reports":                 true,
+	"connections":                     true,
+	"counterparties":                  true,
+	"documents":                       true,
+	"events":                          true,
+	"expected_payments":               true,
+	"external_accounts":               true,
+	"incoming_payment_details":        true,
+	"internal_accounts":               true,
+	"invoice_line_items":              true,
+	"invoices":                        true,
+	"ledger_account_balance_monitors": true,
+	"ledger_account_categories":       true,
+	"ledger_account_payouts":          true,
+	"ledger_account_statements":       true,
+	"ledger_accounts":                 true,
+	"ledger_entries":                  true,
+	"ledger_event_handlers":           true,
+	"ledger_transaction_versions":     true,
+	"ledger_transactions":             true,
+	"ledgerable_events":               true,
+	"ledgers":                         true,
+	"line_items":                      true,
+	"paper_items":                     true,
+	"payment_flows":                   true,
+	"payment_orders":                  true,
+	"payment_references":              true,
+	"returns":                         true,
+	"reversals":                       true,
+	"routing_details":                 true,
+	"transaction_line_items":          true,
+	"transactions":                    true,
+	"virtual_accounts":                true,
+}
+
+// defaults are the fields which are set on objects created in a collection, when
+// they are not given in the request.
+var defaults = map[string]Object{
+	"expected_payments":        {"status": "unreconciled"},
+	"external_accounts":        {"verification_status": "unverified"},
+	"incoming_payment_details": {"status": "completed"},
+	"ledger_transactions":      {"status": "pending"},
+	"ledgers":                  {"metadata": Object{}},
+	"payment_orders":           {"status": "approved", "metadata": Object{}},
+	"reversals":                {"status": "pending"},
+}
+
+// The number of objects in a page, when the request doesn't set `per_page`.
+const defaultPerPage = 25
+
+// Server is a running fake of the Modern Treasury API.
+type Server struct {
+	// The base URL of the server, of the form http://ipaddr:port with no trailing
+	// slash.
+	URL string
+
+	srv *httptest.Server
+
+	mu    sync.Mutex
+	store map[string]*collection
+}
+
+type collection struct {
+	ids     []string
+	objects map[string]Object
+}
+
+// NewServer starts and returns a new Server. The caller should call Close when
+// finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{store: map[string]*collection{}}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Options returns the request options which point a client at the server.
+func (s *Server) Options() []option.RequestOption {
+	return []option.RequestOption{
+		option.WithBaseURL(s.URL),
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+	}
+}
+
+// Add stores an object in the collection at the given path, e.g.
+// "transactions" or "invoices/{id}/invoice_line_items", as if it was created
+// through the API, and returns it. It is useful to seed objects which can't be
+// created through the API, such as transactions and paper items.
+func (s *Server) Add(path string, object Object) Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyObject(s.create(strings.Trim(path, "/"), object))
+}
+
+// Get returns a copy of the object with the given ID in the collection at the
+// given path, or nil if there is none.
+func (s *Server) Get(path string, id string) Object {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.store[strings.Trim(path, "/")]; ok {
+		if object, ok := c.objects[id]; ok {
+			return copyObject(object)
+		}
+	}
+	return nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	path := strings.Trim(req.URL.Path, "/")
+	if !strings.HasPrefix(path, "api/") {
+		writeError(w, http.StatusNotFound, "resource_not_found", "Not found")
+		return
+	}
+	path = strings.TrimPrefix(path, "api/")
+
+	var body Object
+	if req.Method == http.MethodPost || req.Method == http.MethodPatch {
+		var err error
+		if body, err = decodeBody(req); err != nil {
+			writeError(w, http.StatusBadRequest, "parameter_invalid", err.Error())
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case path == "ping":
+		writeJSON(w, http.StatusOK, Object{"ping": "pong"})
+		return
+	case path == "validations/routing_numbers":
+		s.validateRoutingNumber(w, req.URL.Query())
+		return
+	case strings.HasSuffix(path, "/create_async") && req.Method == http.MethodPost:
+		coll := strings.TrimPrefix(strings.TrimSuffix(path, "/create_async"), "simulations/")
+		object := s.create(coll, body)
+		writeJSON(w, http.StatusAccepted, Object{"id": object["id"], "object": object["object"]})
+		return
+	}
+
+	segments := strings.Split(path, "/")
+	n := len(segments)
+	switch {
+	case collections[segments[n-1]]:
+		s.serveCollection(w, req, path, body)
+	case n >= 2 && collections[segments[n-2]]:
+		s.serveObject(w, req, strings.Join(segments[:n-1], "/"), segments[n-1], body)
+	case n >= 3 && collections[segments[n-3]] && req.Method == http.MethodPost:
+		s.serveAction(w, strings.Join(segments[:n-2], "/"), segments[n-2], segments[n-1])
+	default:
+		writeError(w, http.StatusNotFound, "resource_not_found", "Not found")
+	}
+}
+
+func (s *Server) serveCollection(w http.ResponseWriter, req *http.Request, path string, body Object) {
+	if parent, id, ok := parentOf(path); ok && s.lookup(parent, id) == nil {
+		writeNotFound(w, parent, id)
+		return
+	}
+	switch req.Method {
+	case http.MethodGet:
+		s.list(w, path, req.URL.Query())
+	case http.MethodPost:
+		writeJSON(w, http.StatusCreated, s.create(path, body))
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+	}
+}
+
+func (s *Server) serveObject(w http.ResponseWriter, req *http.Request, path string, id string, body Object) {
+	// A PUT adds an existing object to a collection of another, e.g. a ledger
+	// account to a ledger account category.
+	if req.Method == http.MethodPut {
+		parent, parentID, ok := parentOf(path)
+		if !ok || s.lookup(parent, parentID) == nil {
+			writeNotFound(w, parent, parentID)
+			return
+		}
+		object := s.lookup(lastSegment(path), id)
+		if object == nil {
+			writeNotFound(w, lastSegment(path), id)
+			return
+		}
+		s.collection(path).put(id, object)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	object := s.lookup(path, id)
+	if object == nil {
+		writeNotFound(w, path, id)
+		return
+	}
+	switch req.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, object)
+	case http.MethodPatch:
+		writeJSON(w, http.StatusOK, s.update(path, object, body))
+	case http.MethodDelete:
+		s.collection(path).remove(id)
+		if recordsEvents(path) {
+			s.recordEvent(path, "deleted", object)
+		}
+		writeJSON(w, http.StatusOK, object)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+	}
+}
+
+func (s *Server) serveAction(w http.ResponseWriter, path string, id string, action string) {
+	object := s.lookup(path, id)
+	if object == nil {
+		writeNotFound(w, path, id)
+		return
+	}
+	switch path + "/" + action {
+	case "counterparties/collect_account":
+		writeJSON(w, http.StatusOK, Object{
+			"id":        id,
+			"is_resend": false,
+			"form_link": "https://app.moderntreasury.com/public/forms/" + uuid.NewString(),
+		})
+	case "external_accounts/verify":
+		writeJSON(w, http.StatusOK, s.update(path, object, Object{"verification_status": "pending_verification"}))
+	case "external_accounts/complete_verification":
+		writeJSON(w, http.StatusOK, s.update(path, object, Object{"verification_status": "verified"}))
+	case "ledger_transactions/reversal":
+		writeJSON(w, http.StatusCreated, s.create(path, reversalOf(object)))
+	default:
+		writeError(w, http.StatusNotFound, "resource_not_found", "Not found")
+	}
+}
+
+func (s *Server) list(w http.ResponseWriter, path string, query url.Values) {
+	perPage := defaultPerPage
+	if v, err := strconv.Atoi(query.Get("per_page")); err == nil && v > 0 {
+		perPage = v
+	}
+	after := query.Get("after_cursor")
+
+	var items []Object
+	c := s.collection(path)
+	start := 0
+	if after != "" {
+		start = -1
+		for i, id := range c.ids {
+			if id == after {
+				start = i + 1
+			}
+		}
+		// A cursor of an object which doesn't exist, e.g. as it was deleted, can't
+		// be continued from.
+		if start < 0 {
+			writeError(w, http.StatusUnprocessableEntity, "parameter_invalid", fmt.Sprintf("after_cursor %s is invalid", after))
+			return
+		}
+	}
+	next := ""
+	for _, id := range c.ids[start:] {
+		object := c.objects[id]
+		if !matches(object, query) {
+			continue
+		}
+		if len(items) == perPage {
+			next = items[len(items)-1]["id"].(string)
+			break
+		}
+		items = append(items, object)
+	}
+	if items == nil {
+		items = []Object{}
+	}
+	if next != "" {
+		w.Header().Set("X-After-Cursor", next)
+	}
+	w.Header().Set("X-Per-Page", strconv.Itoa(perPage))
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) create(path string, body Object) Object {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	object := Object{}
+	for k, v := range defaults[lastSegment(path)] {
+		object[k] = v
+	}
+	for k, v := range body {
+		object[k] = v
+	}
+	object["id"] = uuid.NewString()
+	object["object"] = singular(lastSegment(path))
+	object["live_mode"] = false
+	object["created_at"] = now
+	object["updated_at"] = now
+	if parent, id, ok := parentOf(path); ok {
+		object[singular(lastSegment(parent))+"_id"] = id
+	}
+
+	if path == "ledger_transactions" {
+		s.createLedgerEntries(object)
+	}
+	s.collection(path).put(object["id"].(string), object)
+	if recordsEvents(path) {
+		s.recordEvent(path, "created", object)
+	}
+	return object
+}
+
+func (s *Server) update(path string, object Object, body Object) Object {
+	status := object["status"]
+	for k, v := range body {
+		if k == "metadata" {
+			object[k] = mergeMetadata(object[k], v)
+			continue
+		}
+		object[k] = v
+	}
+	object["updated_at"] = time.Now().UTC().Format(time.RFC3339Nano)
+	if recordsEvents(path) {
+		// Like the API, an update which changes the status of an object is named
+		// after its new status.
+		name := "updated"
+		if newStatus, ok := object["status"].(string); ok && object["status"] != status {
+			name = newStatus
+		}
+		s.recordEvent(path, name, object)
+	}
+	return object
+}
+
+// createLedgerEntries stores the entries of a new ledger transaction as ledger
+// entries, so that they can be listed.
+func (s *Server) createLedgerEntries(transaction Object) {
+	entries, _ := transaction["ledger_entries"].([]interface{})
+	for i, entry := range entries {
+		fields, ok := entry.(Object)
+		if !ok {
+			continue
+		}
+		fields = copyObject(fields)
+		if account := s.lookup("ledger_accounts", fmt.Sprint(fields["ledger_account_id"])); account != nil && transaction["ledger_id"] == nil {
+			transaction["ledger_id"] = account["ledger_id"]
+		}
+		fields["ledger_transaction_id"] = transaction["id"]
+		fields["status"] = transaction["status"]
+		entries[i] = s.create("ledger_entries", fields)
+	}
+}
+
+// recordsEvents reports whether changes to the objects of a collection are
+// recorded as events, which is the case for top-level objects other than events.
+func recordsEvents(path string) bool {
+	return !strings.Contains(path, "/") && path != "events"
+}
+
+func (s *Server) recordEvent(resource string, name string, object Object) {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	id := uuid.NewString()
+	s.collection("events").put(id, Object{
+		"id":         id,
+		"object":     "event",
+		"live_mode":  false,
+		"created_at": now,
+		"updated_at": now,
+		"event_time": now,
+		"event_name": name,
+		"resource":   singular(resource),
+		"entity_id":  object["id"],
+		"data":       copyObject(object),
+	})
+}
+
+func (s *Server) validateRoutingNumber(w http.ResponseWriter, query url.Values) {
+	number := query.Get("routing_number")
+	if len(number) != 9 {
+		writeError(w, http.StatusUnprocessableEntity, "parameter_invalid", "Routing number is invalid")
+		return
+	}
+	writeJSON(w, http.StatusOK, Object{
+		"routing_number":             number,
+		"routing_number_type":        query.Get("routing_number_type"),
+		"bank_name":                  "Fake Bank",
+		"bank_address":               nil,
+		"supported_payment_types":    []string{"ach", "wire"},
+		"sanctions":                  Object{},
+		"international_routing_code": nil,
+	})
+}
+
+func (s *Server) collection(path string) *collection {
+	c, ok := s.store[path]
+	if !ok {
+		c = &collection{objects: map[string]Object{}}
+		s.store[path] = c
+	}
+	return c
+}
+
+func (s *Server) lookup(path string, id string) Object {
+	if c, ok := s.store[path]; ok {
+		return c.objects[id]
+	}
+	return nil
+}
+
+func (c *collection) put(id string, object Object) {
+	if _, ok := c.objects[id]; !ok {
+		c.ids = append(c.ids, id)
+	}
+	c.objects[id] = object
+}
+
+func (c *collection) remove(id string) {
+	delete(c.objects, id)
+	for i, other := range c.ids {
+		if other == id {
+			c.ids = append(c.ids[:i], c.ids[i+1:]...)
+			break
+		}
+	}
+}
+
+// matches reports whether the object has the values of the query parameters
+// which name its top-level fields, or its metadata in the form `metadata[key]`,
+// and whether its times and dates are within the bounds of the parameters which
+// name them with a `_start` or `_end` suffix. Other parameters are ignored.
+func matches(object Object, query url.Values) bool {
+	for key, values := range query {
+		if key == "after_cursor" || key == "per_page" || len(values) == 0 {
+			continue
+		}
+		if field, bound, ok := timeBound(object, key, values[0]); ok {
+			if strings.HasSuffix(key, "_start") && field.Before(bound) || strings.HasSuffix(key, "_end") && field.After(bound) {
+				return false
+			}
+			continue
+		}
+		if strings.HasPrefix(key, "metadata[") && strings.HasSuffix(key, "]") {
+			metadata, _ := object["metadata"].(Object)
+			if fmt.Sprint(metadata[key[len("metadata["):len(key)-1]]) != values[0] {
+				return false
+			}
+			continue
+		}
+		value, ok := object[key]
+		if !ok || value == nil {
+			continue
+		}
+		switch value.(type) {
+		case Object, []interface{}:
+			continue
+		}
+		if fmt.Sprint(value) != values[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// timeBound returns the time of the field which a query parameter such as
+// `event_time_start` bounds, and the bound, if both are times or dates.
+func timeBound(object Object, key string, value string) (time.Time, time.Time, bool) {
+	name := strings.TrimSuffix(strings.TrimSuffix(key, "_start"), "_end")
+	if name == key {
+		return time.Time{}, time.Time{}, false
+	}
+	field, _ := object[name].(string)
+	fieldTime, ok := parseTime(field)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	bound, ok := parseTime(value)
+	return fieldTime, bound, ok
+}
+
+func parseTime(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func reversalOf(transaction Object) Object {
+	reversal := Object{
+		"ledger_id":   transaction["ledger_id"],
+		"description": transaction["description"],
+		"status":      "posted",
+		"metadata":    Object{},
+	}
+	var entries []interface{}
+	original, _ := transaction["ledger_entries"].([]interface{})
+	for _, entry := range original {
+		fields, ok := entry.(Object)
+		if !ok {
+			continue
+		}
+		direction := "debit"
+		if fields["direction"] == "debit" {
+			direction = "credit"
+		}
+		entries = append(entries, Object{
+			"amount":            fields["amount"],
+			"direction":         direction,
+			"ledger_account_id": fields["ledger_account_id"],
+		})
+	}
+	reversal["ledger_entries"] = entries
+	return reversal
+}
+
+func decodeBody(req *http.Request) (Object, error) {
+	body := Object{}
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		if err := req.ParseMultipartForm(32 << 20); err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(req.MultipartForm.Value))
+		for key := range req.MultipartForm.Value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			body[key] = req.MultipartForm.Value[key][0]
+		}
+		for key, files := range req.MultipartForm.File {
+			body[key] = Object{"filename": files[0].Filename, "size": files[0].Size}
+		}
+		return body, nil
+	}
+	contents, err := io.ReadAll(req.Body)
+	if err != nil || len(contents) == 0 {
+		return body, err
+	}
+	if err := json.Unmarshal(contents, &body); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+	return body, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Request-Id", uuid.NewString())
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, Object{"errors": Object{"code": code, "message": message, "parameter": nil}})
+}
+
+func writeNotFound(w http.ResponseWriter, path string, id string) {
+	writeError(w, http.StatusNotFound, "resource_not_found", fmt.Sprintf("Could not find %s with id %s", singular(lastSegment(path)), id))
+}
+
+// parentOf returns the collection and ID of the object which a nested collection
+// belongs to, e.g. "invoices" and "{id}" for "invoices/{id}/invoice_line_items".
+func parentOf(path string) (string, string, bool) {
+	segments := strings.Split(path, "/")
+	if len(segments) < 3 {
+		return "", "", false
+	}
+	n := len(segments)
+	return strings.Join(segments[:n-2], "/"), segments[n-2], true
+}
+
+func lastSegment(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// singular returns the object type of the objects in a collection, e.g.
+// "counterparty" for "counterparties".
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "s"):
+		return strings.TrimSuffix(name, "s")
+	default:
+		return name
+	}
+}
+
+func mergeMetadata(current interface{}, update interface{}) interface{} {
+	merged := Object{}
+	if current, ok := current.(Object); ok {
+		for k, v := range current {
+			merged[k] = v
+		}
+	}
+	fields, ok := update.(Object)
+	if !ok {
+		return merged
+	}
+	for k, v := range fields {
+		// Like the API, setting a key to an empty string removes it.
+		if v == "" || v == nil {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+func copyObject(object Object) Object {
+	contents, _ := json.Marshal(object)
+	var copied Object
+	_ = json.Unmarshal(contents, &copied)
+	return copied
+}
@@ -0,0 +1,147 @@
+package mtfake_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+	"github.com/Modern-Treasury/modern-treasury-go/testutil/mtfake"
+)
+
+func TestCounterpartyLifecycle(t *testing.T) {
+	srv := mtfake.NewServer()
+	defer srv.Close()
+	client := moderntreasury.NewClient(srv.Options()...)
+	ctx := context.Background()
+
+	var ids []string
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		counterparty, err := client.Counterparties.New(ctx, moderntreasury.CounterpartyNewParams{
+			Name:     moderntreasury.F(name),
+			Metadata: moderntreasury.F(map[string]string{"team": "payments"}),
+		})
+		if err != nil {
+			t.Fatalf("err should be nil: %s", err.Error())
+		}
+		if counterparty.ID == "" || counterparty.CreatedAt.IsZero() || counterparty.Object != "counterparty" {
+			t.Fatalf("expected an ID, timestamps and object type, got %+v", counterparty)
+		}
+		ids = append(ids, counterparty.ID)
+	}
+
+	updated, err := client.Counterparties.Update(ctx, ids[1], moderntreasury.CounterpartyUpdateParams{
+		Name: moderntreasury.F("Robert"),
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if updated.Name != "Robert" || updated.Metadata["team"] != "payments" {
+		t.Fatalf("expected the update to be applied, got %+v", updated)
+	}
+
+	page, err := client.Counterparties.List(ctx, moderntreasury.CounterpartyListParams{PerPage: moderntreasury.F(int64(2))})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if len(page.Items) != 2 || page.Items[1].Name != "Robert" {
+		t.Fatalf("unexpected first page: %+v", page.Items)
+	}
+	page, err = page.GetNextPage()
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != ids[2] {
+		t.Fatalf("unexpected second page: %+v", page.Items)
+	}
+	if page, err = page.GetNextPage(); page != nil || err != nil {
+		t.Fatalf("expected no third page, got %+v, %v", page, err)
+	}
+
+	iter := client.Counterparties.ListAutoPaging(ctx, moderntreasury.CounterpartyListParams{Name: moderntreasury.F("Carol")})
+	var names []string
+	for iter.Next() {
+		names = append(names, iter.Current().Name)
+	}
+	if iter.Err() != nil || len(names) != 1 || names[0] != "Carol" {
+		t.Fatalf("expected the list to be filtered by name, got %v, %v", names, iter.Err())
+	}
+
+	first, err := client.Counterparties.List(ctx, moderntreasury.CounterpartyListParams{PerPage: moderntreasury.F(int64(1))})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if err := client.Counterparties.Delete(ctx, ids[0]); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	_, err = client.Counterparties.Get(ctx, ids[0])
+	var apierr *moderntreasury.Error
+	if !errors.As(err, &apierr) || apierr.StatusCode != http.StatusNotFound || apierr.Errors.Code != "resource_not_found" {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+	// The cursor of the first page is the counterparty which was deleted.
+	if page, err = first.GetNextPage(); !errors.As(err, &apierr) || apierr.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("expected the cursor of a deleted object to be rejected, got %+v, %v", page, err)
+	}
+
+	events, err := client.Events.List(ctx, moderntreasury.EventListParams{EntityID: moderntreasury.F(ids[1])})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if len(events.Items) != 2 || events.Items[0].EventName != "created" || events.Items[1].EventName != "updated" {
+		t.Fatalf("expected created and updated events, got %+v", events.Items)
+	}
+}
+
+func TestLedgerTransactionEntries(t *testing.T) {
+	srv := mtfake.NewServer()
+	defer srv.Close()
+	client := moderntreasury.NewClient(srv.Options()...)
+	ctx := context.Background()
+
+	ledger, err := client.Ledgers.New(ctx, moderntreasury.LedgerNewParams{Name: moderntreasury.F("Operating")})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	var accounts []string
+	for _, name := range []string{"Cash", "Revenue"} {
+		account, err := client.LedgerAccounts.New(ctx, moderntreasury.LedgerAccountNewParams{
+			Currency:      moderntreasury.F("USD"),
+			LedgerID:      moderntreasury.F(ledger.ID),
+			Name:          moderntreasury.F(name),
+			NormalBalance: moderntreasury.F(moderntreasury.LedgerAccountNewParamsNormalBalanceDebit),
+		})
+		if err != nil {
+			t.Fatalf("err should be nil: %s", err.Error())
+		}
+		accounts = append(accounts, account.ID)
+	}
+	transaction, err := client.LedgerTransactions.New(ctx, moderntreasury.LedgerTransactionNewParams{
+		LedgerEntries: moderntreasury.F([]moderntreasury.LedgerTransactionNewParamsLedgerEntry{{
+			Amount:          moderntreasury.F(int64(100)),
+			Direction:       moderntreasury.F(moderntreasury.LedgerTransactionNewParamsLedgerEntriesDirectionCredit),
+			LedgerAccountID: moderntreasury.F(accounts[0]),
+		}, {
+			Amount:          moderntreasury.F(int64(100)),
+			Direction:       moderntreasury.F(moderntreasury.LedgerTransactionNewParamsLedgerEntriesDirectionDebit),
+			LedgerAccountID: moderntreasury.F(accounts[1]),
+		}}),
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if transaction.Status != moderntreasury.LedgerTransactionStatusPending || len(transaction.LedgerEntries) != 2 || transaction.LedgerID != ledger.ID {
+		t.Fatalf("unexpected ledger transaction: %+v", transaction)
+	}
+
+	entries, err := client.LedgerEntries.List(ctx, moderntreasury.LedgerEntryListParams{
+		LedgerTransactionID: moderntreasury.F(transaction.ID),
+	})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if len(entries.Items) != 2 || entries.Items[0].LedgerTransactionID != transaction.ID {
+		t.Fatalf("expected the ledger entries of the transaction, got %+v", entries.Items)
+	}
+}
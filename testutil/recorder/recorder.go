@@ -0,0 +1,301 @@
+// Package recorder records the HTTP interactions of the Modern Treasury client to
+// cassette files, and replays them, so that tests of code using the client run
+// offline and deterministically.
+//
+//	rec, err := recorder.New("testdata/create_payment_order.json", recorder.Options{
+//		Mode: recorder.ModeAuto,
+//	})
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer rec.Stop()
+//	client := moderntreasury.NewClient(option.WithHTTPClient(rec.HTTPClient()))
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+)
+
+// Mode decides whether a [Recorder] records or replays interactions.
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette, and fails requests which were
+	// not recorded.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to the API, and records them to the cassette.
+	ModeRecord
+	// ModeAuto replays the cassette if it exists, and records it otherwise.
+	ModeAuto
+)
+
+// Options configures a [Recorder].
+type Options struct {
+	Mode Mode
+	// The redactor which scrubs secrets from recorded interactions. Defaults to
+	// [option.DefaultRedactor].
+	Redactor option.Redactor
+	// The transport which sends requests when recording. Defaults to
+	// [http.DefaultTransport].
+	Transport http.RoundTripper
+}
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	Path    string      `json:"path"`
+	Query   string      `json:"query,omitempty"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Recorder is an [http.RoundTripper] which records or replays interactions with
+// the API. Requests are matched to recorded interactions on their method, path,
+// query and body; interactions which match are replayed in the order they were
+// recorded, and the last of them is repeated once they are all used.
+type Recorder struct {
+	path      string
+	recording bool
+	redactor  option.Redactor
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// New returns a Recorder for the cassette file at the given path. In replay mode,
+// the cassette is loaded from the file; in record mode, it is written to the file
+// by [Recorder.Stop].
+func New(path string, opts Options) (*Recorder, error) {
+	r := &Recorder{path: path, redactor: opts.Redactor, transport: opts.Transport}
+	if r.redactor == nil {
+		r.redactor = option.DefaultRedactor()
+	}
+	if r.transport == nil {
+		r.transport = http.DefaultTransport
+	}
+
+	switch opts.Mode {
+	case ModeRecord:
+		r.recording = true
+	case ModeAuto:
+		_, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			r.recording = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if r.recording {
+		return r, nil
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(contents, &r.cassette); err != nil {
+		return nil, fmt.Errorf("recorder: invalid cassette %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// Recording reports whether the recorder records interactions, rather than
+// replaying them.
+func (r *Recorder) Recording() bool {
+	return r.recording
+}
+
+// HTTPClient returns an [http.Client] which sends its requests through the
+// recorder, for use with [option.WithHTTPClient].
+func (r *Recorder) HTTPClient() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Middleware is an [option.Middleware] which records the requests that pass
+// through it, or replays them without passing them on.
+func (r *Recorder) Middleware(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	if r.recording {
+		return r.record(req, next)
+	}
+	return r.replay(req)
+}
+
+// RoundTrip records or replays a single request.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.recording {
+		return r.record(req, r.transport.RoundTrip)
+	}
+	return r.replay(req)
+}
+
+// Stop writes the recorded interactions to the cassette file. It does nothing
+// when replaying.
+func (r *Recorder) Stop() error {
+	if !r.recording {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	contents, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(contents, '\n'), 0o644)
+}
+
+func (r *Recorder) record(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	recorded, err := r.recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := next(req)
+	if err != nil {
+		return res, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: res.StatusCode,
+			Headers:    option.RedactHeaders(r.redactor, res.Header),
+			Body:       string(r.redactor.RedactBody(res.Header.Get("Content-Type"), body)),
+		},
+	})
+	return res, nil
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	recorded, err := r.recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	match := -1
+	for i, interaction := range r.cassette.Interactions {
+		if !matches(interaction.Request, recorded) {
+			continue
+		}
+		match = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("recorder: no interaction recorded in %s for %s %s", r.path, req.Method, req.URL.RequestURI())
+	}
+	r.used[match] = true
+
+	response := r.cassette.Interactions[match].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode)),
+		StatusCode:    response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        response.Headers.Clone(),
+		Body:          io.NopCloser(strings.NewReader(response.Body)),
+		ContentLength: int64(len(response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// recordRequest returns the redacted form of the request, without consuming its
+// body.
+func (r *Recorder) recordRequest(req *http.Request) (RecordedRequest, error) {
+	var body []byte
+	if req.GetBody != nil {
+		reader, err := req.GetBody()
+		if err != nil {
+			return RecordedRequest{}, err
+		}
+		body, err = io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return RecordedRequest{}, err
+		}
+	}
+	return RecordedRequest{
+		Method:  req.Method,
+		Path:    req.URL.Path,
+		Query:   canonicalQuery(req.URL.RawQuery),
+		Headers: option.RedactHeaders(r.redactor, req.Header),
+		Body:    canonicalBody(r.redactor.RedactBody(req.Header.Get("Content-Type"), body)),
+	}, nil
+}
+
+func matches(recorded RecordedRequest, req RecordedRequest) bool {
+	return recorded.Method == req.Method &&
+		recorded.Path == req.Path &&
+		canonicalQuery(recorded.Query) == req.Query &&
+		canonicalBody([]byte(recorded.Body)) == req.Body
+}
+
+// canonicalQuery sorts the query parameters, so that their order doesn't affect
+// matching.
+func canonicalQuery(raw string) string {
+	query, err := url.ParseQuery(raw)
+	if err != nil {
+		return raw
+	}
+	return query.Encode()
+}
+
+// canonicalBody re-encodes JSON bodies with sorted keys and no whitespace, so that
+// their formatting doesn't affect matching.
+func canonicalBody(body []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return string(body)
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return string(body)
+	}
+	return string(canonical)
+}
@@ -0,0 +1,92 @@
+package recorder_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+	"github.com/Modern-Treasury/modern-treasury-go/testutil/recorder"
+)
+
+// counterpartyTransport responds to every request with a counterparty, and
+// counts the requests it receives.
+type counterpartyTransport struct {
+	count int
+}
+
+func (t *counterpartyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.count++
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"id":"cp_123","name":"Alice","accounts":[{"account_details":[{"account_number":"123456789"}]}]}`)),
+		Request:    req,
+	}, nil
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	transport := &counterpartyTransport{}
+	rec, err := recorder.New(path, recorder.Options{Mode: recorder.ModeAuto, Transport: transport})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if !rec.Recording() {
+		t.Fatal("expected the recorder to record when the cassette does not exist")
+	}
+	client := moderntreasury.NewClient(
+		option.WithBaseURL("http://127.0.0.1:4010"),
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithHTTPClient(rec.HTTPClient()),
+	)
+	if _, err := client.Counterparties.Get(context.Background(), "cp_123"); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if err := rec.Stop(); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if strings.Contains(string(contents), "123456789") || strings.Contains(string(contents), "APIKey") {
+		t.Fatalf("expected secrets to be scrubbed from the cassette, got %s", contents)
+	}
+
+	rec, err = recorder.New(path, recorder.Options{Mode: recorder.ModeAuto, Transport: transport})
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if rec.Recording() {
+		t.Fatal("expected the recorder to replay when the cassette exists")
+	}
+	client = moderntreasury.NewClient(
+		option.WithBaseURL("http://127.0.0.1:4010"),
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithHTTPClient(rec.HTTPClient()),
+		option.WithMaxRetries(0),
+	)
+	counterparty, err := client.Counterparties.Get(context.Background(), "cp_123")
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if counterparty.Name != "Alice" {
+		t.Fatalf("expected the recorded counterparty, got %+v", counterparty)
+	}
+	if transport.count != 1 {
+		t.Fatalf("expected replay not to send requests, sent %d", transport.count)
+	}
+
+	if _, err := client.Counterparties.Get(context.Background(), "cp_456"); err == nil {
+		t.Fatal("expected an error for a request which was not recorded")
+	}
+}
@@ -0,0 +1,412 @@
+// Package webhooktest builds signed webhooks like those sent by Modern Treasury,
+// to test webhook consumers without a tunnel to the internet:
+//
+//	emitter := webhooktest.NewEmitter("my-webhook-key")
+//	webhook := emitter.Webhook("payment_order", "completed", webhooktest.Object{"amount": 1000})
+//	res := emitter.Serve(handler, webhook)
+//
+// Webhooks have realistic envelopes and data for each topic, and are signed with
+// [moderntreasury.WebhookService.GetSignature]. They are sent to an
+// [http.Handler] directly with Serve, or to a URL with Post. The scenario
+// helpers build the deliveries which consumers must be robust to: duplicates,
+// out-of-order deliveries and bad signatures.
+package webhooktest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+	"github.com/google/uuid"
+)
+
+// Object is an object of the API, as it is encoded in JSON.
+type Object = map[string]interface{}
+
+// Webhook is a webhook to deliver.
+type Webhook struct {
+	// Sent as the `X-Webhook-ID` header. Deliveries of the same webhook share
+	// their ID.
+	ID string
+	// The type of the object in Data, e.g. "payment_order", sent as the `X-Topic`
+	// header.
+	Topic string
+	// The name of the event, e.g. "completed".
+	Event string
+	// Sent as the `X-Live-Mode` header.
+	LiveMode bool
+	// The time the webhook was sent, sent as the `X-Timestamp` header in Unix
+	// seconds. Omitted if zero.
+	Timestamp time.Time
+	// The object which the event is about.
+	Data Object
+	// If set, sent as the `X-Signature` header instead of the signature of the
+	// body.
+	Signature string
+}
+
+// Body returns the JSON body of the webhook.
+func (w Webhook) Body() []byte {
+	body, _ := json.Marshal(Object{"event": w.Event, "data": w.Data})
+	return body
+}
+
+// Emitter signs and delivers webhooks.
+type Emitter struct {
+	key     string
+	service *moderntreasury.WebhookService
+
+	// Returns the time at which webhooks are built. Defaults to [time.Now].
+	Now func() time.Time
+	// The client which Post sends webhooks with. Defaults to
+	// [http.DefaultClient].
+	HTTPClient *http.Client
+}
+
+// NewEmitter returns an Emitter which signs webhooks with the given key.
+func NewEmitter(key string) *Emitter {
+	return &Emitter{
+		key:        key,
+		service:    moderntreasury.NewWebhookService(),
+		Now:        time.Now,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Webhook returns a webhook for the event of the topic, with realistic data for
+// an object of the topic, over which the given fields are set.
+func (e *Emitter) Webhook(topic string, event string, fields Object) Webhook {
+	now := e.Now().UTC()
+	data := Object{
+		"id":         uuid.NewString(),
+		"object":     topic,
+		"live_mode":  false,
+		"created_at": now.Format(time.RFC3339),
+		"updated_at": now.Format(time.RFC3339),
+	}
+	for k, v := range topicDefaults(topic, now) {
+		data[k] = v
+	}
+	if status, ok := eventStatus(topic, event); ok {
+		data[statusField(topic)] = status
+	}
+	for k, v := range fields {
+		data[k] = v
+	}
+	return Webhook{
+		ID:        uuid.NewString(),
+		Topic:     topic,
+		Event:     event,
+		Timestamp: now,
+		Data:      data,
+	}
+}
+
+// Lifecycle returns a webhook for each of the events of one object of the topic,
+// in order, e.g. "created", "approved" and "completed" of a payment order. The
+// status of the object follows its events, and its `updated_at` advances by a
+// second for each event.
+func (e *Emitter) Lifecycle(topic string, fields Object, events ...string) []Webhook {
+	first := e.Webhook(topic, "", fields)
+	webhooks := make([]Webhook, len(events))
+	for i, event := range events {
+		w := first
+		w.ID = uuid.NewString()
+		w.Event = event
+		w.Timestamp = first.Timestamp.Add(time.Duration(i) * time.Second)
+		w.Data = Object{}
+		for k, v := range first.Data {
+			w.Data[k] = v
+		}
+		w.Data["updated_at"] = w.Timestamp.Format(time.RFC3339)
+		if status, ok := eventStatus(topic, event); ok {
+			w.Data[statusField(topic)] = status
+		}
+		if status, ok := fields[statusField(topic)]; ok {
+			w.Data[statusField(topic)] = status
+		}
+		webhooks[i] = w
+	}
+	return webhooks
+}
+
+// Sign returns the `X-Signature` of the webhook: its Signature if set, or
+// otherwise the signature of its body.
+func (e *Emitter) Sign(w Webhook) string {
+	if w.Signature != "" {
+		return w.Signature
+	}
+	signature, _ := e.service.GetSignature(w.Body(), e.key)
+	return signature
+}
+
+// header returns the headers which Modern Treasury sends with the webhook.
+func (e *Emitter) header(w Webhook) http.Header {
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("X-Signature", e.Sign(w))
+	header.Set("X-Topic", w.Topic)
+	header.Set("X-Webhook-ID", w.ID)
+	header.Set("X-Live-Mode", strconv.FormatBool(w.LiveMode))
+	if !w.Timestamp.IsZero() {
+		header.Set("X-Timestamp", strconv.FormatInt(w.Timestamp.Unix(), 10))
+	}
+	return header
+}
+
+// NewRequest returns a signed request which delivers the webhook to the URL.
+func (e *Emitter) NewRequest(ctx context.Context, url string, w Webhook) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(w.Body()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = e.header(w)
+	return req, nil
+}
+
+// Post delivers the webhook to the URL.
+func (e *Emitter) Post(ctx context.Context, url string, w Webhook) (*http.Response, error) {
+	req, err := e.NewRequest(ctx, url, w)
+	if err != nil {
+		return nil, err
+	}
+	return e.HTTPClient.Do(req)
+}
+
+// Serve delivers the webhook to the handler, and returns its response.
+func (e *Emitter) Serve(h http.Handler, w Webhook) *http.Response {
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(w.Body()))
+	req.Header = e.header(w)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Result()
+}
+
+// ServeAll delivers the webhooks to the handler in order, and returns the status
+// codes of its responses.
+func (e *Emitter) ServeAll(h http.Handler, webhooks ...Webhook) []int {
+	codes := make([]int, len(webhooks))
+	for i, w := range webhooks {
+		codes[i] = e.Serve(h, w).StatusCode
+	}
+	return codes
+}
+
+// Duplicates returns n deliveries of the webhook, which share its ID.
+func Duplicates(w Webhook, n int) []Webhook {
+	webhooks := make([]Webhook, n)
+	for i := range webhooks {
+		webhooks[i] = w
+	}
+	return webhooks
+}
+
+// Reversed returns the webhooks in reverse order, e.g. to deliver the events of a
+// lifecycle from last to first.
+func Reversed(webhooks ...Webhook) []Webhook {
+	reversed := make([]Webhook, len(webhooks))
+	for i, w := range webhooks {
+		reversed[len(webhooks)-1-i] = w
+	}
+	return reversed
+}
+
+// Shuffled returns the webhooks in a random order.
+func Shuffled(webhooks ...Webhook) []Webhook {
+	shuffled := append([]Webhook(nil), webhooks...)
+	for i := len(shuffled) - 1; i > 0; i-- {
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		j := int(n.Int64())
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	}
+	return shuffled
+}
+
+// BadSignature returns the webhook signed with another key, which consumers must
+// reject.
+func BadSignature(w Webhook) Webhook {
+	signature, _ := moderntreasury.NewWebhookService().GetSignature(w.Body(), "not-"+uuid.NewString())
+	w.Signature = signature
+	return w
+}
+
+// topicDefaults returns realistic fields of an object of the topic.
+func topicDefaults(topic string, now time.Time) Object {
+	date := now.Format("2006-01-02")
+	switch topic {
+	case "payment_order":
+		return Object{
+			"type":                   "ach",
+			"amount":                 1000,
+			"currency":               "USD",
+			"direction":              "credit",
+			"priority":               "normal",
+			"status":                 "approved",
+			"effective_date":         date,
+			"originating_account_id": uuid.NewString(),
+			"receiving_account_id":   uuid.NewString(),
+			"counterparty_id":        uuid.NewString(),
+			"description":            "Payment",
+			"metadata":               Object{},
+			"reference_numbers":      []interface{}{},
+		}
+	case "expected_payment":
+		return Object{
+			"type":                "ach",
+			"amount_lower_bound":  1000,
+			"amount_upper_bound":  1000,
+			"currency":            "USD",
+			"direction":           "credit",
+			"status":              "unreconciled",
+			"internal_account_id": uuid.NewString(),
+			"date_lower_bound":    date,
+			"date_upper_bound":    date,
+			"metadata":            Object{},
+		}
+	case "incoming_payment_detail":
+		return Object{
+			"type":                "ach",
+			"amount":              1000,
+			"currency":            "USD",
+			"direction":           "credit",
+			"status":              "completed",
+			"as_of_date":          date,
+			"internal_account_id": uuid.NewString(),
+			"metadata":            Object{},
+		}
+	case "transaction":
+		return Object{
+			"type":                "ach",
+			"amount":              1000,
+			"currency":            "USD",
+			"direction":           "credit",
+			"as_of_date":          date,
+			"posted":              true,
+			"internal_account_id": uuid.NewString(),
+			"vendor_code_type":    "bai2",
+			"metadata":            Object{},
+		}
+	case "return":
+		return Object{
+			"type":            "ach",
+			"amount":          1000,
+			"currency":        "USD",
+			"code":            "R01",
+			"reason":          "Insufficient funds",
+			"status":          "completed",
+			"returnable_id":   uuid.NewString(),
+			"returnable_type": "payment_order",
+		}
+	case "reversal":
+		return Object{
+			"payment_order_id": uuid.NewString(),
+			"reason":           "duplicate",
+			"status":           "pending",
+			"metadata":         Object{},
+		}
+	case "counterparty":
+		return Object{
+			"name":     "Acme Corp",
+			"email":    "billing@example.com",
+			"accounts": []interface{}{},
+			"metadata": Object{},
+		}
+	case "external_account":
+		return Object{
+			"name":                "Operating account",
+			"account_type":        "checking",
+			"counterparty_id":     uuid.NewString(),
+			"verification_status": "unverified",
+			"metadata":            Object{},
+		}
+	case "ledger_transaction":
+		return Object{
+			"ledger_id":      uuid.NewString(),
+			"description":    "Transfer",
+			"status":         "pending",
+			"effective_date": date,
+			"ledger_entries": []interface{}{},
+			"metadata":       Object{},
+		}
+	case "paper_item":
+		return Object{
+			"amount":         1000,
+			"currency":       "USD",
+			"status":         "pending",
+			"deposit_date":   date,
+			"lockbox_number": "1234",
+			"check_number":   "1001",
+			"remitter_name":  "Acme Corp",
+		}
+	}
+	return Object{}
+}
+
+// eventStatuses maps the events of each topic which change the status of its
+// object to the new status.
+var eventStatuses = map[string]map[string]string{
+	"payment_order": {
+		"approved":         "approved",
+		"begin_processing": "processing",
+		"cancelled":        "cancelled",
+		"completed":        "completed",
+		"denied":           "denied",
+		"failed":           "failed",
+		"needs_approval":   "needs_approval",
+		"returned":         "returned",
+		"reversed":         "reversed",
+		"sent":             "sent",
+	},
+	"expected_payment": {
+		"reconciled":   "reconciled",
+		"unreconciled": "unreconciled",
+		"archived":     "archived",
+	},
+	"incoming_payment_detail": {
+		"completed": "completed",
+		"failed":    "failed",
+	},
+	"return": {
+		"completed": "completed",
+		"failed":    "failed",
+		"sent":      "sent",
+	},
+	"reversal": {
+		"completed":  "completed",
+		"failed":     "failed",
+		"processing": "processing",
+		"returned":   "returned",
+	},
+	"external_account": {
+		"verified":            "verified",
+		"verification_failed": "unverified",
+	},
+	"ledger_transaction": {
+		"posted":   "posted",
+		"archived": "archived",
+	},
+	"paper_item": {
+		"completed": "completed",
+	},
+}
+
+func eventStatus(topic string, event string) (string, bool) {
+	status, ok := eventStatuses[topic][event]
+	return status, ok
+}
+
+// statusField returns the field which holds the status of an object of the
+// topic.
+func statusField(topic string) string {
+	if topic == "external_account" {
+		return "verification_status"
+	}
+	return "status"
+}
@@ -0,0 +1,70 @@
+package webhooktest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+	"github.com/Modern-Treasury/modern-treasury-go/testutil/webhooktest"
+)
+
+func TestEmitter(t *testing.T) {
+	client := moderntreasury.NewClient(option.WithWebhookKey("key"))
+	var statuses []moderntreasury.PaymentOrderStatus
+	handler := client.Webhooks.Handler(moderntreasury.WebhookHandlerOptions{Store: moderntreasury.NewMemoryWebhookSeenStore()}).
+		OnPaymentOrder(func(ctx context.Context, event moderntreasury.WebhookEvent, paymentOrder *moderntreasury.PaymentOrder) error {
+			statuses = append(statuses, paymentOrder.Status)
+			return nil
+		})
+	emitter := webhooktest.NewEmitter("key")
+
+	// Out of order deliveries of a lifecycle.
+	lifecycle := emitter.Lifecycle("payment_order", webhooktest.Object{"amount": 2500}, "created", "begin_processing", "completed")
+	for i, code := range emitter.ServeAll(handler, webhooktest.Reversed(lifecycle...)...) {
+		if code != http.StatusOK {
+			t.Fatalf("delivery %d: expected 200, got %d", i, code)
+		}
+	}
+	want := []moderntreasury.PaymentOrderStatus{
+		moderntreasury.PaymentOrderStatusCompleted,
+		moderntreasury.PaymentOrderStatusProcessing,
+		moderntreasury.PaymentOrderStatusApproved,
+	}
+	if len(statuses) != len(want) {
+		t.Fatalf("expected statuses %v, got %v", want, statuses)
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("expected statuses %v, got %v", want, statuses)
+		}
+	}
+
+	// Duplicates are acknowledged, but only processed once.
+	statuses = nil
+	webhook := emitter.Webhook("payment_order", "failed", nil)
+	emitter.ServeAll(handler, webhooktest.Duplicates(webhook, 3)...)
+	if len(statuses) != 1 || statuses[0] != moderntreasury.PaymentOrderStatusFailed {
+		t.Fatalf("expected one failed payment order, got %v", statuses)
+	}
+
+	// Bad signatures are rejected.
+	bad := emitter.Webhook("payment_order", "completed", nil)
+	if res := emitter.Serve(handler, webhooktest.BadSignature(bad)); res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", res.StatusCode)
+	}
+
+	// Webhooks are posted to servers.
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	res, err := emitter.Post(context.Background(), srv.URL, emitter.Webhook("expected_payment", "reconciled", nil))
+	if err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+}
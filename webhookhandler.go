@@ -0,0 +1,326 @@
+package moderntreasury
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// WebhookEvent is the envelope of a webhook sent by Modern Treasury.
+type WebhookEvent struct {
+	// The ID of the webhook, from the `X-Webhook-ID` header. Deliveries of the same
+	// webhook share its ID.
+	ID string `json:"-"`
+	// The type of the object in Data, e.g. "payment_order", from the `object`
+	// field of the signed data. The handler rejects webhooks whose `X-Topic`
+	// header disagrees with it.
+	Topic string `json:"-"`
+	// Whether the webhook is for an object in the live environment, from the
+	// `X-Live-Mode` header.
+	LiveMode bool `json:"-"`
+	// The index in the active webhook keys of the key which the webhook's
+	// signature matched. Webhooks signed with a key which is being rotated out
+	// have an index greater than 0.
+	KeyIndex int `json:"-"`
+	// The name of the event, e.g. "completed".
+	Event string `json:"event"`
+	// The object which the event is about, as JSON.
+	Data json.RawMessage `json:"data"`
+}
+
+// WebhookHandlerOptions configures the handler returned by
+// [WebhookService.Handler].
+type WebhookHandlerOptions struct {
+	// The keys which webhooks may be signed with, in order of preference. Defaults
+	// to the webhook keys of the client, from [option.WithWebhookKeys],
+	// [option.WithWebhookKey] or the MODERN_TREASURY_WEBHOOK_KEY environment
+	// variable.
+	Keys []string
+	// The largest body which is read, in bytes. Larger webhooks are rejected with
+	// 413 Request Entity Too Large. Defaults to 1 MiB.
+	MaxBodyBytes int64
+	// How far the `X-Timestamp` of a webhook may be from the current time, as in
+	// [WebhookVerifyOptions]. Defaults to 5 minutes.
+	Tolerance time.Duration
+	// If set, webhooks are de-duplicated by a hash of their signed payload, as in
+	// [WebhookService.VerifyWebhook]: webhooks which were already processed are
+	// acknowledged without calling their callback, and webhooks which are being
+	// processed are rejected with 409 Conflict so that they are retried. Webhooks
+	// are only recorded as processed once their callback succeeds, and webhooks
+	// which fail are forgotten, so that their retries are processed. Webhooks
+	// without an `X-Webhook-ID` header are rejected with 400 Bad Request.
+	Store WebhookSeenStore
+	// How long webhooks are recorded as processing, and then as processed, as in
+	// [WebhookVerifyOptions]. Default to 1 minute and 24 hours.
+	ProcessingTTL time.Duration
+	SeenTTL       time.Duration
+	// OnError is called with the error of each webhook which isn't acknowledged
+	// with a 2xx response, e.g. to log it.
+	OnError func(req *http.Request, err error)
+}
+
+// WebhookStatusError is an error which is returned from the callbacks of a
+// [WebhookHandler] to respond to the webhook with the given status code. Modern
+// Treasury retries webhooks which aren't acknowledged with a 2xx response.
+type WebhookStatusError struct {
+	StatusCode int
+	Err        error
+}
+
+// WebhookStatus returns an error which makes a [WebhookHandler] respond with the
+// given status code, e.g. 422 Unprocessable Entity for a webhook which is
+// invalid for the application, or 200 OK for a webhook which is ignored.
+func WebhookStatus(statusCode int, err error) error {
+	return &WebhookStatusError{StatusCode: statusCode, Err: err}
+}
+
+func (e *WebhookStatusError) Error() string {
+	if e.Err == nil {
+		return http.StatusText(e.StatusCode)
+	}
+	return e.Err.Error()
+}
+
+func (e *WebhookStatusError) Unwrap() error {
+	return e.Err
+}
+
+// WebhookHandler is an [http.Handler] which receives webhooks from Modern
+// Treasury. It verifies the `X-Signature` of each webhook, decodes it, and calls
+// the callback registered for its topic. Webhooks are acknowledged with 200 OK
+// when the callback succeeds, or when no callback is registered for their topic.
+// Otherwise they are answered with:
+//   - 405 Method Not Allowed, for requests other than POST
+//   - 413 Request Entity Too Large, for bodies larger than MaxBodyBytes
+//   - 401 Unauthorized, for a missing or invalid signature, or a timestamp
+//     outside of the tolerance window
+//   - 400 Bad Request, for bodies which can't be decoded
+//   - the status code of a [WebhookStatusError] returned by the callback
+//   - 503 Service Unavailable, when the request's context is done
+//   - 500 Internal Server Error, for other errors of the callback
+//
+// Callbacks are registered with the On methods, and may be registered while the
+// handler is serving.
+type WebhookHandler struct {
+	service *WebhookService
+	keys    []string
+	opts    WebhookHandlerOptions
+
+	mu        sync.RWMutex
+	callbacks map[string]func(ctx context.Context, event WebhookEvent) error
+	fallback  func(ctx context.Context, event WebhookEvent) error
+}
+
+// Handler returns a [WebhookHandler] for the webhooks of the client, to which
+// callbacks are registered with its On methods.
+func (r *WebhookService) Handler(opts WebhookHandlerOptions) *WebhookHandler {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = 1 << 20
+	}
+	keys := opts.Keys
+	if len(keys) == 0 {
+		keys = r.webhookKeys()
+	}
+	return &WebhookHandler{
+		service:   r,
+		keys:      keys,
+		opts:      opts,
+		callbacks: map[string]func(ctx context.Context, event WebhookEvent) error{},
+	}
+}
+
+// On registers the callback for the webhooks of the given topic, e.g.
+// "payment_order", replacing any callback which was registered for it.
+func (h *WebhookHandler) On(topic string, callback func(ctx context.Context, event WebhookEvent) error) *WebhookHandler {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.callbacks[topic] = callback
+	return h
+}
+
+// OnOther registers the callback for the webhooks of topics which have no
+// callback of their own.
+func (h *WebhookHandler) OnOther(callback func(ctx context.Context, event WebhookEvent) error) *WebhookHandler {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fallback = callback
+	return h
+}
+
+// onObject registers a callback which receives the data of the webhooks of the
+// topic decoded as T.
+func onObject[T any](h *WebhookHandler, topic string, callback func(ctx context.Context, event WebhookEvent, object *T) error) *WebhookHandler {
+	return h.On(topic, func(ctx context.Context, event WebhookEvent) error {
+		object := new(T)
+		if err := json.Unmarshal(event.Data, object); err != nil {
+			return WebhookStatus(http.StatusBadRequest, fmt.Errorf("invalid %s in webhook: %w", topic, err))
+		}
+		return callback(ctx, event, object)
+	})
+}
+
+// OnPaymentOrder registers the callback for payment order webhooks.
+func (h *WebhookHandler) OnPaymentOrder(callback func(ctx context.Context, event WebhookEvent, paymentOrder *PaymentOrder) error) *WebhookHandler {
+	return onObject(h, "payment_order", callback)
+}
+
+// OnExpectedPayment registers the callback for expected payment webhooks.
+func (h *WebhookHandler) OnExpectedPayment(callback func(ctx context.Context, event WebhookEvent, expectedPayment *ExpectedPayment) error) *WebhookHandler {
+	return onObject(h, "expected_payment", callback)
+}
+
+// OnIncomingPaymentDetail registers the callback for incoming payment detail
+// webhooks.
+func (h *WebhookHandler) OnIncomingPaymentDetail(callback func(ctx context.Context, event WebhookEvent, incomingPaymentDetail *IncomingPaymentDetail) error) *WebhookHandler {
+	return onObject(h, "incoming_payment_detail", callback)
+}
+
+// OnTransaction registers the callback for transaction webhooks.
+func (h *WebhookHandler) OnTransaction(callback func(ctx context.Context, event WebhookEvent, transaction *Transaction) error) *WebhookHandler {
+	return onObject(h, "transaction", callback)
+}
+
+// OnCounterparty registers the callback for counterparty webhooks.
+func (h *WebhookHandler) OnCounterparty(callback func(ctx context.Context, event WebhookEvent, counterparty *Counterparty) error) *WebhookHandler {
+	return onObject(h, "counterparty", callback)
+}
+
+// OnExternalAccount registers the callback for external account webhooks.
+func (h *WebhookHandler) OnExternalAccount(callback func(ctx context.Context, event WebhookEvent, externalAccount *ExternalAccount) error) *WebhookHandler {
+	return onObject(h, "external_account", callback)
+}
+
+// OnReturn registers the callback for return webhooks.
+func (h *WebhookHandler) OnReturn(callback func(ctx context.Context, event WebhookEvent, returnObject *ReturnObject) error) *WebhookHandler {
+	return onObject(h, "return", callback)
+}
+
+// OnReversal registers the callback for payment order reversal webhooks.
+func (h *WebhookHandler) OnReversal(callback func(ctx context.Context, event WebhookEvent, reversal *Reversal) error) *WebhookHandler {
+	return onObject(h, "reversal", callback)
+}
+
+// OnLedgerTransaction registers the callback for ledger transaction webhooks.
+func (h *WebhookHandler) OnLedgerTransaction(callback func(ctx context.Context, event WebhookEvent, ledgerTransaction *LedgerTransaction) error) *WebhookHandler {
+	return onObject(h, "ledger_transaction", callback)
+}
+
+// OnPaperItem registers the callback for paper item webhooks.
+func (h *WebhookHandler) OnPaperItem(callback func(ctx context.Context, event WebhookEvent, paperItem *PaperItem) error) *WebhookHandler {
+	return onObject(h, "paper_item", callback)
+}
+
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	verified, err := h.serve(req)
+	status := http.StatusOK
+	if err != nil {
+		status = http.StatusInternalServerError
+		var statusErr *WebhookStatusError
+		switch {
+		case errors.As(err, &statusErr):
+			status = statusErr.StatusCode
+		case req.Context().Err() != nil:
+			status = http.StatusServiceUnavailable
+		}
+	}
+	succeeded := status >= 200 && status <= 299
+	// A webhook is only recorded as processed once it succeeded, and is forgotten
+	// when it failed, so that its retry is processed.
+	if verified != nil && h.opts.Store != nil {
+		if succeeded {
+			_ = h.service.MarkWebhookProcessed(context.Background(), verified, WebhookVerifyOptions{Store: h.opts.Store, SeenTTL: h.opts.SeenTTL})
+		} else {
+			_ = h.opts.Store.Forget(context.Background(), webhookReplayKey(verified))
+		}
+	}
+	if !succeeded {
+		if h.opts.OnError != nil {
+			h.opts.OnError(req, err)
+		}
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	w.WriteHeader(status)
+}
+
+// serve handles the webhook, and returns its body if it was verified, so that it
+// is recorded as processed when it succeeds and forgotten when it fails.
+func (h *WebhookHandler) serve(req *http.Request) (verified []byte, err error) {
+	if req.Method != http.MethodPost {
+		return nil, WebhookStatus(http.StatusMethodNotAllowed, fmt.Errorf("webhooks must be sent with POST, not %s", req.Method))
+	}
+	if len(h.keys) == 0 {
+		return nil, errors.New("no webhook key is configured to verify webhooks with")
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, h.opts.MaxBodyBytes+1))
+	if err != nil {
+		return nil, WebhookStatus(http.StatusBadRequest, err)
+	}
+	if int64(len(body)) > h.opts.MaxBodyBytes {
+		return nil, WebhookStatus(http.StatusRequestEntityTooLarge, fmt.Errorf("webhook body is larger than %d bytes", h.opts.MaxBodyBytes))
+	}
+
+	keyIndex, err := h.service.VerifyWebhook(req.Context(), body, req.Header, WebhookVerifyOptions{
+		Keys:          h.keys,
+		Tolerance:     h.opts.Tolerance,
+		Store:         h.opts.Store,
+		ProcessingTTL: h.opts.ProcessingTTL,
+	})
+	switch {
+	case errors.Is(err, ErrReplayed):
+		return nil, WebhookStatus(http.StatusOK, err)
+	case errors.Is(err, ErrInProgress):
+		return nil, WebhookStatus(http.StatusConflict, err)
+	case errors.Is(err, ErrMissingWebhookID):
+		return nil, WebhookStatus(http.StatusBadRequest, err)
+	case errors.Is(err, ErrMissingSignature), errors.Is(err, ErrInvalidSignature), errors.Is(err, ErrTimestampOutOfTolerance):
+		return nil, WebhookStatus(http.StatusUnauthorized, err)
+	case err != nil:
+		return nil, err
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return body, WebhookStatus(http.StatusBadRequest, fmt.Errorf("invalid webhook body: %w", err))
+	}
+	// The callback is chosen by the type of the signed data, as the `X-Topic`
+	// header isn't signed.
+	var object struct {
+		Object string `json:"object"`
+	}
+	if err := json.Unmarshal(event.Data, &object); err != nil || object.Object == "" {
+		return body, WebhookStatus(http.StatusBadRequest, errors.New("invalid webhook body: the data has no object type"))
+	}
+	if topic := req.Header.Get("X-Topic"); topic != "" && topic != object.Object {
+		return body, WebhookStatus(http.StatusBadRequest, fmt.Errorf("the X-Topic header %q doesn't match the %q object of the webhook", topic, object.Object))
+	}
+	event.ID = req.Header.Get("X-Webhook-ID")
+	event.Topic = object.Object
+	event.LiveMode, _ = strconv.ParseBool(req.Header.Get("X-Live-Mode"))
+	event.KeyIndex = keyIndex
+
+	return body, h.Dispatch(req.Context(), event)
+}
+
+// Dispatch calls the callback registered for the topic of the event, if any, and
+// returns its error. It lets other sources of events, such as an
+// [EventConsumer], share the callbacks of the handler.
+func (h *WebhookHandler) Dispatch(ctx context.Context, event WebhookEvent) error {
+	h.mu.RLock()
+	callback, ok := h.callbacks[event.Topic]
+	if !ok {
+		callback = h.fallback
+	}
+	h.mu.RUnlock()
+	if callback == nil {
+		return nil
+	}
+	return callback(ctx, event)
+}
@@ -0,0 +1,152 @@
+package moderntreasury_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	client := moderntreasury.NewClient(
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithWebhookKey("key"),
+	)
+
+	var got *moderntreasury.PaymentOrder
+	handler := client.Webhooks.Handler(moderntreasury.WebhookHandlerOptions{MaxBodyBytes: 256}).
+		OnPaymentOrder(func(ctx context.Context, event moderntreasury.WebhookEvent, paymentOrder *moderntreasury.PaymentOrder) error {
+			if event.Event != "completed" || event.ID != "wh_123" {
+				t.Fatalf("unexpected event: %+v", event)
+			}
+			got = paymentOrder
+			return nil
+		}).
+		OnExpectedPayment(func(ctx context.Context, event moderntreasury.WebhookEvent, expectedPayment *moderntreasury.ExpectedPayment) error {
+			return moderntreasury.WebhookStatus(http.StatusUnprocessableEntity, errors.New("unknown expected payment"))
+		}).
+		OnTransaction(func(ctx context.Context, event moderntreasury.WebhookEvent, transaction *moderntreasury.Transaction) error {
+			return errors.New("database is down")
+		})
+
+	send := func(method string, topic string, body string, signature string) int {
+		req := httptest.NewRequest(method, "/webhooks", strings.NewReader(body))
+		if signature == "" {
+			signature, _ = client.Webhooks.GetSignature([]byte(body), "key")
+		}
+		req.Header.Set("X-Signature", signature)
+		req.Header.Set("X-Topic", topic)
+		req.Header.Set("X-Webhook-ID", "wh_123")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	body := `{"event":"completed","data":{"id":"po_123","object":"payment_order","amount":1000,"status":"completed"}}`
+	if code := send(http.MethodPost, "payment_order", body, ""); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if got == nil || got.ID != "po_123" || got.Amount != 1000 || got.Status != moderntreasury.PaymentOrderStatusCompleted {
+		t.Fatalf("unexpected payment order: %+v", got)
+	}
+
+	cases := []struct {
+		name      string
+		method    string
+		topic     string
+		body      string
+		signature string
+		code      int
+	}{
+		{"wrong method", http.MethodGet, "payment_order", body, "", http.StatusMethodNotAllowed},
+		{"invalid signature", http.MethodPost, "payment_order", body, "0123", http.StatusUnauthorized},
+		{"too large", http.MethodPost, "payment_order", `{"data":"` + strings.Repeat("a", 256) + `"}`, "", http.StatusRequestEntityTooLarge},
+		{"malformed", http.MethodPost, "payment_order", `{"data":`, "", http.StatusBadRequest},
+		{"no object type", http.MethodPost, "payment_order", `{"event":"created","data":{}}`, "", http.StatusBadRequest},
+		{"topic mismatch", http.MethodPost, "expected_payment", body, "", http.StatusBadRequest},
+		{"unhandled topic", http.MethodPost, "counterparty", `{"event":"created","data":{"object":"counterparty"}}`, "", http.StatusOK},
+		{"status error", http.MethodPost, "expected_payment", `{"event":"created","data":{"object":"expected_payment"}}`, "", http.StatusUnprocessableEntity},
+		{"callback error", http.MethodPost, "transaction", `{"event":"created","data":{"object":"transaction"}}`, "", http.StatusInternalServerError},
+		{"no topic header", http.MethodPost, "", `{"event":"created","data":{"object":"transaction"}}`, "", http.StatusInternalServerError},
+	}
+	for _, c := range cases {
+		if code := send(c.method, c.topic, c.body, c.signature); code != c.code {
+			t.Fatalf("%s: expected %d, got %d", c.name, c.code, code)
+		}
+	}
+}
+
+func TestWebhookHandlerDeduplicates(t *testing.T) {
+	client := moderntreasury.NewClient(
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithWebhookKey("key"),
+	)
+
+	calls := 0
+	store := moderntreasury.NewMemoryWebhookSeenStore()
+	handler := client.Webhooks.Handler(moderntreasury.WebhookHandlerOptions{Store: store}).
+		OnPaymentOrder(func(ctx context.Context, event moderntreasury.WebhookEvent, paymentOrder *moderntreasury.PaymentOrder) error {
+			calls++
+			if calls == 1 {
+				return errors.New("database is down")
+			}
+			return nil
+		})
+
+	send := func(body string, id string) int {
+		signature, _ := client.Webhooks.GetSignature([]byte(body), "key")
+		req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+		req.Header.Set("X-Signature", signature)
+		req.Header.Set("X-Topic", "payment_order")
+		if id != "" {
+			req.Header.Set("X-Webhook-ID", id)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// The failed webhook is forgotten, so its retry is processed, and then later
+	// deliveries are acknowledged without calling the callback, even when their
+	// unsigned ID is changed.
+	body := `{"event":"completed","data":{"id":"po_123","object":"payment_order"}}`
+	for i, id := range []string{"wh_123", "wh_123", "wh_123", "wh_999"} {
+		code := http.StatusOK
+		if i == 0 {
+			code = http.StatusInternalServerError
+		}
+		if got := send(body, id); got != code {
+			t.Fatalf("delivery %d: expected %d, got %d", i, code, got)
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+	if got := send(`{"event":"completed","data":{"id":"po_789","object":"payment_order"}}`, ""); got != http.StatusBadRequest {
+		t.Fatalf("expected a webhook without an ID to be rejected with 400, got %d", got)
+	}
+
+	// A webhook which is being processed elsewhere is rejected, so that it is
+	// retried rather than acknowledged before it was processed.
+	body = `{"event":"completed","data":{"id":"po_456","object":"payment_order"}}`
+	sum := sha256.Sum256([]byte(body))
+	if _, err := store.MarkSeen(context.Background(), hex.EncodeToString(sum[:]), time.Minute); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if got := send(body, "wh_456"); got != http.StatusConflict {
+		t.Fatalf("expected a webhook in progress to be rejected with 409, got %d", got)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+}
@@ -0,0 +1,285 @@
+package moderntreasury
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Modern-Treasury/modern-treasury-go/internal/requestconfig"
+)
+
+var (
+	// ErrMissingSignature is returned when a webhook has no `X-Signature` header.
+	ErrMissingSignature = errors.New("Could not find an X-Signature header")
+	// ErrInvalidSignature is returned when the `X-Signature` of a webhook doesn't
+	// match its payload.
+	ErrInvalidSignature = errors.New("webhook signature is invalid")
+	// ErrTimestampOutOfTolerance is returned when the timestamp of a webhook is
+	// further from the current time than the tolerance allows.
+	ErrTimestampOutOfTolerance = errors.New("webhook timestamp is outside of the tolerance window")
+	// ErrMissingWebhookID is returned when a webhook has no `X-Webhook-ID`
+	// header, and replays are rejected.
+	ErrMissingWebhookID = errors.New("Could not find an X-Webhook-ID header")
+	// ErrReplayed is returned when a webhook with the same payload was already
+	// processed.
+	ErrReplayed = errors.New("webhook was already received")
+	// ErrInProgress is returned when a webhook with the same payload is being
+	// processed.
+	ErrInProgress = errors.New("webhook is already being processed")
+)
+
+// WebhookSeenState is the state of a webhook key in a [WebhookSeenStore].
+type WebhookSeenState int
+
+const (
+	// WebhookUnseen is the state of an ID which isn't recorded.
+	WebhookUnseen WebhookSeenState = iota
+	// WebhookProcessing is the state of an ID whose webhook is being processed.
+	WebhookProcessing
+	// WebhookProcessed is the state of an ID whose webhook was processed.
+	WebhookProcessed
+)
+
+// WebhookSeenStore records the keys of webhooks which are being processed or
+// were processed, so that replayed webhooks are rejected. The key of a webhook is
+// a hash of its signed payload. A key is first recorded as processing for a short
+// time, so that a webhook whose process crashed is accepted again once it
+// expires, and then as processed once its webhook was processed. Implementations
+// must be safe for concurrent use, and may be backed by a shared store such as
+// Redis when webhooks are received by more than one process.
+type WebhookSeenStore interface {
+	// MarkSeen records the key as processing for the given duration, unless it is
+	// already recorded, and returns the state it was in. Recording and checking
+	// must be atomic.
+	MarkSeen(ctx context.Context, key string, ttl time.Duration) (WebhookSeenState, error)
+	// MarkProcessed records the key as processed for the given duration.
+	MarkProcessed(ctx context.Context, key string, ttl time.Duration) error
+	// Forget removes the key, so that a webhook which failed to be processed is
+	// accepted when it is retried.
+	Forget(ctx context.Context, key string) error
+}
+
+// NewMemoryWebhookSeenStore returns a [WebhookSeenStore] which keeps the keys in
+// memory.
+func NewMemoryWebhookSeenStore() WebhookSeenStore {
+	return &memoryWebhookSeenStore{ids: map[string]memoryWebhookSeen{}}
+}
+
+type memoryWebhookSeenStore struct {
+	mu  sync.Mutex
+	ids map[string]memoryWebhookSeen
+}
+
+type memoryWebhookSeen struct {
+	state   WebhookSeenState
+	expires time.Time
+}
+
+func (s *memoryWebhookSeenStore) MarkSeen(ctx context.Context, key string, ttl time.Duration) (WebhookSeenState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for seen, record := range s.ids {
+		if now.After(record.expires) {
+			delete(s.ids, seen)
+		}
+	}
+	if record, ok := s.ids[key]; ok {
+		return record.state, nil
+	}
+	s.ids[key] = memoryWebhookSeen{state: WebhookProcessing, expires: now.Add(ttl)}
+	return WebhookUnseen, nil
+}
+
+func (s *memoryWebhookSeenStore) MarkProcessed(ctx context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids[key] = memoryWebhookSeen{state: WebhookProcessed, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *memoryWebhookSeenStore) Forget(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.ids, key)
+	return nil
+}
+
+// WebhookVerifyOptions configures [WebhookService.VerifyWebhook].
+type WebhookVerifyOptions struct {
+	// The keys which webhooks may be signed with, in order of preference. Defaults
+	// to the webhook keys of the client, from [option.WithWebhookKeys],
+	// [option.WithWebhookKey] or the MODERN_TREASURY_WEBHOOK_KEY environment
+	// variable.
+	Keys []string
+	// The header which holds the time a webhook was sent, as Unix seconds or
+	// RFC 3339. Defaults to `X-Timestamp`.
+	TimestampHeader string
+	// How far the timestamp of a webhook may be from the current time. Webhooks
+	// without a timestamp header aren't checked. Defaults to 5 minutes.
+	Tolerance time.Duration
+	// If set, the key of each verified webhook, a hash of its payload, is recorded
+	// in the store as processing, and webhooks whose key was already recorded are
+	// rejected with [ErrInProgress] or [ErrReplayed]. Webhooks without an
+	// `X-Webhook-ID` header are rejected with [ErrMissingWebhookID]. Once the
+	// webhook was processed, it must be recorded with
+	// [WebhookService.MarkWebhookProcessed], or its key forgotten if it failed.
+	Store WebhookSeenStore
+	// How long keys are recorded as processing, after which the webhook is
+	// accepted again, e.g. when the process which received it crashed. It should
+	// be longer than webhooks take to process. Defaults to 1 minute.
+	ProcessingTTL time.Duration
+	// How long keys are kept in the store once their webhook was processed.
+	// Defaults to 24 hours.
+	SeenTTL time.Duration
+	// Returns the current time. Defaults to [time.Now].
+	Now func() time.Time
+}
+
+// VerifyWebhook verifies that the webhook payload was sent by Modern Treasury,
+// and returns the index in the keys of the key which its signature matched, so
+// that a key which is rotated can be retired once no webhooks match it. If the
+// webhook can't be verified, it returns [ErrMissingSignature],
+// [ErrInvalidSignature], [ErrTimestampOutOfTolerance], [ErrMissingWebhookID],
+// [ErrInProgress] or [ErrReplayed]. Signatures are compared in constant time.
+//
+// The signature only covers the payload: the `X-Timestamp` and `X-Webhook-ID`
+// headers aren't signed. Replays are therefore detected by a hash of the payload,
+// which an attacker can't change without invalidating the signature, rather than
+// by the ID. The timestamp check only guards against stale deliveries.
+func (r *WebhookService) VerifyWebhook(ctx context.Context, payload []byte, headers http.Header, opts WebhookVerifyOptions) (keyIndex int, err error) {
+	if len(opts.Keys) == 0 {
+		opts.Keys = r.webhookKeys()
+	}
+	if len(opts.Keys) == 0 {
+		return -1, errors.New("no webhook key is configured to verify webhooks with")
+	}
+	if opts.TimestampHeader == "" {
+		opts.TimestampHeader = "X-Timestamp"
+	}
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = 5 * time.Minute
+	}
+	if opts.ProcessingTTL <= 0 {
+		opts.ProcessingTTL = defaultWebhookProcessingTTL
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+
+	signature := headers.Get("X-Signature")
+	if signature == "" {
+		return -1, ErrMissingSignature
+	}
+	keyIndex = -1
+	for i, key := range opts.Keys {
+		if r.signatureMatches(payload, key, signature) {
+			keyIndex = i
+			break
+		}
+	}
+	if keyIndex < 0 {
+		return -1, ErrInvalidSignature
+	}
+
+	if value := headers.Get(opts.TimestampHeader); value != "" {
+		timestamp, err := parseWebhookTimestamp(value)
+		if err != nil {
+			return keyIndex, fmt.Errorf("%w: %s", ErrTimestampOutOfTolerance, err)
+		}
+		if diff := opts.Now().Sub(timestamp); diff > opts.Tolerance || diff < -opts.Tolerance {
+			return keyIndex, ErrTimestampOutOfTolerance
+		}
+	}
+
+	if opts.Store != nil {
+		if headers.Get("X-Webhook-ID") == "" {
+			return keyIndex, ErrMissingWebhookID
+		}
+		state, err := opts.Store.MarkSeen(ctx, webhookReplayKey(payload), opts.ProcessingTTL)
+		if err != nil {
+			return keyIndex, err
+		}
+		switch state {
+		case WebhookProcessing:
+			return keyIndex, ErrInProgress
+		case WebhookProcessed:
+			return keyIndex, ErrReplayed
+		}
+	}
+	return keyIndex, nil
+}
+
+const (
+	defaultWebhookProcessingTTL = time.Minute
+	defaultWebhookSeenTTL       = 24 * time.Hour
+)
+
+// MarkWebhookProcessed records the payload of a webhook which was verified with a
+// Store in the options as processed, so that later deliveries of it are rejected
+// with [ErrReplayed].
+func (r *WebhookService) MarkWebhookProcessed(ctx context.Context, payload []byte, opts WebhookVerifyOptions) error {
+	if opts.Store == nil {
+		return nil
+	}
+	if opts.SeenTTL <= 0 {
+		opts.SeenTTL = defaultWebhookSeenTTL
+	}
+	return opts.Store.MarkProcessed(ctx, webhookReplayKey(payload), opts.SeenTTL)
+}
+
+// webhookReplayKey returns the key of the webhook in a [WebhookSeenStore], the
+// hex encoded SHA-256 of its payload. Only the payload is signed, so no other
+// part of the webhook can be trusted to tell deliveries apart.
+func webhookReplayKey(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// signatureMatches compares the hex encoded signature with the signature of the
+// payload in constant time.
+func (r *WebhookService) signatureMatches(payload []byte, key string, signature string) bool {
+	expected, err := r.GetSignature(payload, key)
+	if err != nil {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	want, _ := hex.DecodeString(expected)
+	return hmac.Equal(got, want)
+}
+
+// webhookKeys returns the webhook keys configured in the options of the service.
+func (r *WebhookService) webhookKeys() []string {
+	cfg, err := requestconfig.NewRequestConfig(context.Background(), http.MethodPost, "", nil, nil, r.Options...)
+	if err != nil {
+		return nil
+	}
+	if len(cfg.WebhookKeys) > 0 {
+		return cfg.WebhookKeys
+	}
+	if cfg.WebhookKey == "" {
+		return nil
+	}
+	return []string{cfg.WebhookKey}
+}
+
+func parseWebhookTimestamp(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid webhook timestamp %q", value)
+	}
+	return timestamp, nil
+}
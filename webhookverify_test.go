@@ -0,0 +1,113 @@
+package moderntreasury_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	moderntreasury "github.com/Modern-Treasury/modern-treasury-go"
+	"github.com/Modern-Treasury/modern-treasury-go/option"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	client := moderntreasury.NewClient(
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithWebhookKey("key"),
+	)
+	payload := []byte(`{"event":"created","data":{}}`)
+	signature, _ := client.Webhooks.GetSignature(payload, "key")
+	other := []byte(`{"event":"created","data":{"id":"cp_123"}}`)
+	otherSignature, _ := client.Webhooks.GetSignature(other, "key")
+	now := time.Unix(1700000000, 0)
+	opts := moderntreasury.WebhookVerifyOptions{
+		Store: moderntreasury.NewMemoryWebhookSeenStore(),
+		Now:   func() time.Time { return now },
+	}
+
+	headers := func(signature string, timestamp time.Time) http.Header {
+		h := http.Header{}
+		if signature != "" {
+			h.Set("X-Signature", signature)
+		}
+		h.Set("X-Webhook-ID", "wh_"+strconv.FormatInt(timestamp.Unix(), 10))
+		h.Set("X-Timestamp", strconv.FormatInt(timestamp.Unix(), 10))
+		return h
+	}
+	withoutID := headers(otherSignature, now)
+	withoutID.Del("X-Webhook-ID")
+
+	cases := []struct {
+		name    string
+		payload []byte
+		headers http.Header
+		err     error
+	}{
+		{"valid", payload, headers(signature, now), nil},
+		{"in progress", payload, headers(signature, now), moderntreasury.ErrInProgress},
+		{"in progress with another ID", payload, headers(signature, now.Add(time.Second)), moderntreasury.ErrInProgress},
+		{"missing signature", other, headers("", now), moderntreasury.ErrMissingSignature},
+		{"invalid signature", other, headers("abcd", now), moderntreasury.ErrInvalidSignature},
+		{"not hex", other, headers("zz", now), moderntreasury.ErrInvalidSignature},
+		{"stale", other, headers(otherSignature, now.Add(-time.Hour)), moderntreasury.ErrTimestampOutOfTolerance},
+		{"missing ID", other, withoutID, moderntreasury.ErrMissingWebhookID},
+		{"within tolerance", other, headers(otherSignature, now.Add(-time.Minute)), nil},
+	}
+	for _, c := range cases {
+		_, err := client.Webhooks.VerifyWebhook(context.Background(), c.payload, c.headers, opts)
+		if !errors.Is(err, c.err) || (c.err == nil && err != nil) {
+			t.Fatalf("%s: expected %v, got %v", c.name, c.err, err)
+		}
+	}
+
+	// Once the webhook was processed, its deliveries are replays.
+	if err := client.Webhooks.MarkWebhookProcessed(context.Background(), payload, opts); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	if _, err := client.Webhooks.VerifyWebhook(context.Background(), payload, headers(signature, now), opts); !errors.Is(err, moderntreasury.ErrReplayed) {
+		t.Fatalf("expected ErrReplayed, got %v", err)
+	}
+
+	// A webhook which was never marked as processed, e.g. as its process crashed,
+	// is accepted again once its claim expires.
+	opts.ProcessingTTL = time.Millisecond
+	crashed := []byte(`{"event":"created","data":{"id":"cp_456"}}`)
+	crashedSignature, _ := client.Webhooks.GetSignature(crashed, "key")
+	if _, err := client.Webhooks.VerifyWebhook(context.Background(), crashed, headers(crashedSignature, now), opts); err != nil {
+		t.Fatalf("err should be nil: %s", err.Error())
+	}
+	time.Sleep(2 * time.Millisecond)
+	if _, err := client.Webhooks.VerifyWebhook(context.Background(), crashed, headers(crashedSignature, now), opts); err != nil {
+		t.Fatalf("expected the expired claim to be accepted again, got %v", err)
+	}
+}
+
+func TestVerifyWebhookRotatedKeys(t *testing.T) {
+	client := moderntreasury.NewClient(
+		option.WithAPIKey("APIKey"),
+		option.WithOrganizationID("my-organization-ID"),
+		option.WithWebhookKeys("new", "old"),
+	)
+	payload := []byte(`{"event":"created","data":{}}`)
+
+	for i, key := range []string{"new", "old"} {
+		signature, _ := client.Webhooks.GetSignature(payload, key)
+		headers := http.Header{"X-Signature": {signature}}
+		keyIndex, err := client.Webhooks.VerifyWebhook(context.Background(), payload, headers, moderntreasury.WebhookVerifyOptions{})
+		if err != nil {
+			t.Fatalf("err should be nil: %s", err.Error())
+		}
+		if keyIndex != i {
+			t.Fatalf("expected the signature of %q to match key %d, got %d", key, i, keyIndex)
+		}
+	}
+
+	signature, _ := client.Webhooks.GetSignature(payload, "retired")
+	headers := http.Header{"X-Signature": {signature}}
+	if _, err := client.Webhooks.VerifyWebhook(context.Background(), payload, headers, moderntreasury.WebhookVerifyOptions{}); !errors.Is(err, moderntreasury.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+}